@@ -2,26 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/blang/semver/v4"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters/addons/metallb"
 	"github.com/kong/kubernetes-testing-framework/pkg/environments"
 	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/aks"
 	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/eks"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/existing"
 	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/gke"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/k3d"
 	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/kind"
 	"github.com/kumahq/kuma-smoke/pkg/utils"
 	"github.com/kumahq/kuma-smoke/test"
+	k8s_suite "github.com/kumahq/kuma-smoke/test/kubernetes"
+	"github.com/kumahq/kuma/test/framework"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 type deployOptions struct {
 	k8sVersionOptions
 	envOptions
 	kubeconfigOptions
+	stateOutputFile string
+	loadImages      []string
 }
 
 var k8sDeployOpt = deployOptions{}
@@ -29,66 +44,384 @@ var k8sDeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "deploy the cluster and product that the smoke tests will be running on",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		var err error
-		k8sDeployOpt.parsedK8sVersion, err = semver.Parse(strings.TrimPrefix(k8sDeployOpt.kubernetesVersion, "v"))
+		err := parseKubernetesVersion(cmd, &k8sDeployOpt.k8sVersionOptions)
 		cobra.CheckErr(err)
 
-		kumaMinSupported := semver.MustParse(test.MinSupportedKubernetesVer)
-		if k8sDeployOpt.parsedK8sVersion.Major < kumaMinSupported.Major ||
-			(k8sDeployOpt.parsedK8sVersion.Major == kumaMinSupported.Major && k8sDeployOpt.parsedK8sVersion.Minor < kumaMinSupported.Minor) {
-			utils.CmdStdErr(cmd, "Warning: deploying a Kubernetes cluster older than the minimal supported version by Kuma. "+
-				"The minimal supported version by Kuma is %s\n", test.MinSupportedKubernetesVer)
-		}
-
 		err = validatePlatformName(k8sDeployOpt.envPlatform)
 		cobra.CheckErr(err)
 
+		err = k8sDeployOpt.resolveClusterOptions(k8sDeployOpt.envOptions)
+		cobra.CheckErr(err)
+
+		if len(k8sDeployOpt.loadImages) > 0 {
+			err = validateImageLoading(k8sDeployOpt.envPlatform)
+			cobra.CheckErr(err)
+		}
+
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
 		defer cancel()
 
-		envBuilder := environments.NewBuilder()
-		randomName := strings.Replace(envBuilder.Name, "-", "", -1)
-		envBuilder = envBuilder.WithName("kuma-smoke-" + randomName[len(randomName)-10:])
+		envName := utils.NewEnvName()
+		if err := deployKubernetes(ctx, cmd, envName); err != nil {
+			utils.CmdStdErr(cmd, "failed to deploy environment %s, cleaning it up\n", envName)
+			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+			defer cleanupCancel()
+			cleanupErr := cleanupEnvironment(cleanupCtx, cmd, k8sDeployOpt.envPlatform, envName, k8sDeployOpt.clusterOpts)
+			// the state file is kept when the cleanup fails, so that the cleanup can be retried with it
+			if cleanupErr == nil && k8sDeployOpt.stateOutputFile != "" {
+				_ = os.Remove(k8sDeployOpt.stateOutputFile)
+			}
+			return errors.Join(err, cleanupErr)
+		}
+		return nil
+	},
+}
+
+// deployKubernetes creates the environment of the deploy command and writes its state and kubeconfig. The state is
+// written before creating the cluster and again once it is created, so that a failed deployment can always be cleaned up.
+func deployKubernetes(ctx context.Context, cmd *cobra.Command, envName string) error {
+	if k8sDeployOpt.stateOutputFile != "" {
+		state := &cluster_providers.EnvironmentState{
+			Provider:          k8sDeployOpt.envPlatform,
+			Name:              envName,
+			KubernetesVersion: k8sDeployOpt.parsedK8sVersion.String(),
+			CreatedAt:         time.Now().UTC(),
+			Region:            k8sDeployOpt.region,
+		}
+		if err := state.Write(k8sDeployOpt.stateOutputFile); err != nil {
+			return err
+		}
+	}
+
+	env, err := deployEnvironment(ctx, cmd, k8sDeployOpt.envPlatform, envName, k8sDeployOpt.clusterOpts, true)
+	if err != nil {
+		return err
+	}
+
+	if k8sDeployOpt.stateOutputFile != "" {
+		state := newEnvironmentState(k8sDeployOpt.envPlatform, env, k8sDeployOpt.clusterOpts)
+		if err := state.Write(k8sDeployOpt.stateOutputFile); err != nil {
+			return err
+		}
+	}
+
+	if err := loadImages(ctx, cmd, k8sDeployOpt.envPlatform, env.Name(), k8sDeployOpt.loadImages); err != nil {
+		return err
+	}
+
+	if k8sDeployOpt.kubeconfigOutputFile != "" {
+		kubeconfig := cluster_providers.KubeconfigRestConfig(k8sDeployOpt.envPlatform, env.Name(), env.Cluster(), k8sDeployOpt.clusterOpts)
+		return utils.WriteKubeconfig(env.Name(), cmd, kubeconfig, k8sDeployOpt.kubeconfigOutputFile)
+	}
+	utils.CmdStdout(cmd, "%s", env.Name())
+	return nil
+}
+
+func parseKubernetesVersion(cmd *cobra.Command, opts *k8sVersionOptions) error {
+	var err error
+	opts.parsedK8sVersion, err = semver.Parse(strings.TrimPrefix(opts.kubernetesVersion, "v"))
+	if err != nil {
+		return err
+	}
+
+	kumaMinSupported := semver.MustParse(test.MinSupportedKubernetesVer)
+	if opts.parsedK8sVersion.Major < kumaMinSupported.Major ||
+		(opts.parsedK8sVersion.Major == kumaMinSupported.Major && opts.parsedK8sVersion.Minor < kumaMinSupported.Minor) {
+		utils.CmdStdErr(cmd, "Warning: deploying a Kubernetes cluster older than the minimal supported version by Kuma. "+
+			"The minimal supported version by Kuma is %s\n", test.MinSupportedKubernetesVer)
+	}
+	return nil
+}
+
+// newEnvironmentState records the created environment, the Kubernetes version reported by the cluster
+// is preferred over the requested one, since some platforms only honor the minor version
+func newEnvironmentState(platform string, env environments.Environment, opts cluster_providers.Options) *cluster_providers.EnvironmentState {
+	state := &cluster_providers.EnvironmentState{
+		Provider:          platform,
+		Name:              env.Name(),
+		KubernetesVersion: opts.KubernetesVersion.String(),
+		CreatedAt:         time.Now().UTC(),
+		Region:            cluster_providers.ClusterRegion(env.Cluster(), opts),
+	}
+	if version, err := env.Cluster().Version(); err == nil {
+		state.KubernetesVersion = version.String()
+	}
+	if reporter, ok := env.Cluster().(cluster_providers.ResourceReporter); ok {
+		state.Resources = reporter.Resources()
+	}
+	return state
+}
+
+// deployEnvironment builds a new environment on the platform and waits for it to become ready.
+// withLoadBalancer deploys metallb on kind clusters, so that LoadBalancer services can get an address.
+func deployEnvironment(ctx context.Context, cmd *cobra.Command, platform, envName string,
+	opts cluster_providers.Options, withLoadBalancer bool) (environments.Environment, error) {
+	envBuilder := environments.NewBuilder().WithName(envName)
+
+	clsBuilder, err := cluster_providers.GetBuilder(ctx, platform, envBuilder.Name, opts)
+	if err != nil {
+		return nil, err
+	}
+	envBuilder = envBuilder.WithClusterBuilder(clsBuilder)
+	if platform == "kind" && withLoadBalancer {
+		envBuilder = envBuilder.WithAddons(metallb.New())
+	}
+
+	utils.CmdStdErr(cmd, "building new environment %s\n", envBuilder.Name)
+	env, err := envBuilder.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addons := env.Cluster().ListAddons()
+	for _, addon := range addons {
+		utils.CmdStdErr(cmd, "waiting for addon %s to become ready...\n", addon.Name())
+	}
+
+	utils.CmdStdErr(cmd, "waiting for environment %s to become ready (this can take some time)...\n", env.Name())
+	if err := <-env.WaitForReady(ctx); err != nil {
+		return nil, err
+	}
+
+	utils.CmdStdErr(cmd, "environment %s was created successfully!\n", env.Name())
+	return env, nil
+}
+
+// loadImages side-loads images into the nodes of the environment, e.g. the images of Kuma built from a branch
+func loadImages(ctx context.Context, cmd *cobra.Command, platform, envName string, images []string) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	utils.CmdStdErr(cmd, "loading images %s into environment %s\n", strings.Join(images, ", "), envName)
+	return cluster_providers.LoadImages(ctx, platform, envName, images)
+}
+
+func validateImageLoading(platform string) error {
+	provider, err := cluster_providers.GetProvider(platform)
+	if err != nil {
+		return err
+	}
+	if _, ok := provider.(cluster_providers.ImageLoader); !ok {
+		return fmt.Errorf("loading images is not supported on platform %s", platform)
+	}
+	return nil
+}
+
+type k8sRunOptions struct {
+	k8sVersionOptions
+	envOptions
+	stateOptions
+	productOptions
+	fetchOptions
+	knownReleasesOptions
+	kumactlBin       string
+	prevMinorVersion string
+	prevMinorKumactl string
+	prevPatchVersion string
+	prevPatchKumactl string
+	debugDir         string
+	jsonReportFile   string
+	timeout          time.Duration
+	keepEnv          bool
+	loadImages       []string
+	imageRegistry    string
+	imageTag         string
+}
 
-		clsBuilder, err := cluster_providers.GetBuilder(k8sDeployOpt.envPlatform, cmd, envBuilder.Name)
+var k8sRunOpt = k8sRunOptions{}
+var k8sRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "deploy a cluster, run the smoke tests on it and clean it up, all inside this process",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		err := parseKubernetesVersion(cmd, &k8sRunOpt.k8sVersionOptions)
 		cobra.CheckErr(err)
-		if clsBuilder != nil {
-			envBuilder = envBuilder.WithClusterBuilder(clsBuilder)
-		} else {
-			envBuilder = envBuilder.WithKubernetesVersion(k8sDeployOpt.parsedK8sVersion)
+
+		if k8sRunOpt.stateFile != "" {
+			envState, err := cluster_providers.LoadEnvironmentState(k8sRunOpt.stateFile)
+			cobra.CheckErr(err)
+			k8sRunOpt.envOptions.restoreFromState(envState)
 		}
-		if k8sDeployOpt.envPlatform == "kind" {
-			envBuilder = envBuilder.WithAddons(metallb.New())
+
+		err = validatePlatformName(k8sRunOpt.envPlatform)
+		cobra.CheckErr(err)
+
+		err = k8sRunOpt.resolveClusterOptions(k8sRunOpt.envOptions)
+		cobra.CheckErr(err)
+
+		err = validateProductName(k8sRunOpt.productName)
+		cobra.CheckErr(err)
+
+		if len(k8sRunOpt.loadImages) > 0 {
+			err = validateImageLoading(k8sRunOpt.envPlatform)
+			cobra.CheckErr(err)
+		}
+
+		if k8sRunOpt.prevMinorVersion == "" {
+			upgradeFrom, err := resolveUpgradeFrom(k8sRunOpt.productVersion, k8sRunOpt.knownReleasesOptions)
+			cobra.CheckErr(err)
+
+			k8sRunOpt.prevMinorVersion = upgradeFrom.PrevMinor.String()
+			if upgradeFrom.PrevPatch != nil && k8sRunOpt.prevPatchVersion == "" {
+				k8sRunOpt.prevPatchVersion = upgradeFrom.PrevPatch.String()
+			}
+			utils.CmdStdErr(cmd, "upgrade tests will start from previous minor version %s and previous patch version %s\n",
+				k8sRunOpt.prevMinorVersion, k8sRunOpt.prevPatchVersion)
 		}
 
-		utils.CmdStdErr(cmd, "building new environment %s\n", envBuilder.Name)
-		env, err := envBuilder.Build(ctx)
+		err = fetchMissingKumactl(cmd, &k8sRunOpt)
 		cobra.CheckErr(err)
 
-		addons := env.Cluster().ListAddons()
-		for _, addon := range addons {
-			utils.CmdStdErr(cmd, "waiting for addon %s to become ready...\n", addon.Name())
+		// the test framework configuration is loaded before creating the cluster, so that a misconfiguration fails fast
+		err = loadKubernetesSuiteConfig(k8sRunOpt)
+		cobra.CheckErr(err)
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		// an environment deployed beforehand is neither created nor cleaned up by the run
+		existingEnv := k8sRunOpt.envName != ""
+		envName := k8sRunOpt.envName
+		if !existingEnv {
+			envName = utils.NewEnvName()
+			_, err := deployEnvironment(ctx, cmd, k8sRunOpt.envPlatform, envName, k8sRunOpt.clusterOpts, true)
+			if err != nil {
+				return errors.Join(err, cleanupAfterRun(cmd, envName))
+			}
 		}
 
-		utils.CmdStdErr(cmd, "waiting for environment to become ready (this can take some time)...\n")
-		cobra.CheckErr(<-env.WaitForReady(ctx))
+		if err := loadImages(ctx, cmd, k8sRunOpt.envPlatform, envName, k8sRunOpt.loadImages); err != nil {
+			if existingEnv {
+				return err
+			}
+			return errors.Join(err, cleanupAfterRun(cmd, envName))
+		}
 
-		utils.CmdStdErr(cmd, "environment %s was created successfully!\n", env.Name())
+		err := k8s_suite.RegisterSuite(k8s_suite.SuiteOptions{
+			EnvPlatform:      k8sRunOpt.envPlatform,
+			EnvName:          envName,
+			ClusterOptions:   k8sRunOpt.clusterOpts,
+			PrevMinorVersion: k8sRunOpt.prevMinorVersion,
+			PrevMinorKumactl: k8sRunOpt.prevMinorKumactl,
+			PrevPatchVersion: k8sRunOpt.prevPatchVersion,
+			PrevPatchKumactl: k8sRunOpt.prevPatchKumactl,
+			ImageRegistry:    k8sRunOpt.imageRegistry,
+			ImageTag:         k8sRunOpt.imageTag,
+		})
+		if err == nil {
+			utils.CmdStdErr(cmd, "running smoke tests of %s %s on environment %s\n", k8sRunOpt.productName, k8sRunOpt.productVersion, envName)
+			passed := test.RunSpecs("Kuma Smoke Suite - Kubernetes", test.RunOptions{
+				Timeout:        k8sRunOpt.timeout,
+				JSONReportFile: k8sRunOpt.jsonReportFile,
+			})
+			if !passed {
+				err = errors.New("smoke tests failed")
+			}
+		}
 
-		if k8sDeployOpt.kubeconfigOutputFile != "" {
-			cobra.CheckErr(utils.WriteKubeconfig(envBuilder.Name, cmd, env.Cluster().Config(), k8sDeployOpt.kubeconfigOutputFile))
-		} else {
-			utils.CmdStdout(cmd, "%s", env.Name())
+		if k8sRunOpt.keepEnv || existingEnv {
+			utils.CmdStdErr(cmd, "keeping environment %s\n", envName)
+			return err
 		}
+		return errors.Join(err, cleanupAfterRun(cmd, envName))
+	},
+}
+
+// fetchMissingKumactl fetches kumactl of the tested versions that are not provided by flags
+func fetchMissingKumactl(cmd *cobra.Command, opts *k8sRunOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+	defer cancel()
+
+	for _, bin := range []struct {
+		path    *string
+		version string
+	}{
+		{&opts.kumactlBin, opts.productVersion},
+		{&opts.prevMinorKumactl, opts.prevMinorVersion},
+		{&opts.prevPatchKumactl, opts.prevPatchVersion},
+	} {
+		if *bin.path != "" || bin.version == "" {
+			continue
+		}
+
+		kumactlPath, err := fetchKumactl(ctx, cmd, opts.fetchOptions, opts.productName, bin.version)
+		if err != nil {
+			return err
+		}
+		*bin.path = kumactlPath
+	}
+	return nil
+}
+
+// loadKubernetesSuiteConfig configures the Kuma test framework from the flags of the run command,
+// they replace the environment variables that are passed to ginkgo when running the suite with "make run"
+func loadKubernetesSuiteConfig(opts k8sRunOptions) error {
+	if err := test.LoadE2EConfig(opts.productName, opts.envPlatform); err != nil {
+		return err
+	}
+
+	debugDir, err := filepath.Abs(opts.debugDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(debugDir, 0o755); err != nil {
+		return err
+	}
+
+	framework.Config.KumactlBin = opts.kumactlBin
+	framework.Config.KumaImageTag = opts.productVersion
+	framework.Config.DebugDir = debugDir
+	return framework.Config.Validate()
+}
+
+// cleanupAfterRun deletes the environment created by the run command. A new context is used,
+// since the context used for creating the environment may already have expired.
+func cleanupAfterRun(cmd *cobra.Command, envName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+	defer cancel()
+
+	return cleanupEnvironment(ctx, cmd, k8sRunOpt.envPlatform, envName, k8sRunOpt.clusterOpts)
+}
+
+type k8sLoadImagesOptions struct {
+	envOptions
+	stateOptions
+	images []string
+}
+
+var k8sLoadImagesOpt = k8sLoadImagesOptions{}
+var k8sLoadImagesCmd = &cobra.Command{
+	Use:   "load-images",
+	Short: "load images into the nodes of a created cluster, e.g. the images of Kuma that are not published yet",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := resolveEnvFromState(&k8sLoadImagesOpt.envOptions, k8sLoadImagesOpt.stateOptions)
+		cobra.CheckErr(err)
+
+		err = validatePlatformName(k8sLoadImagesOpt.envPlatform)
+		cobra.CheckErr(err)
+
+		err = validateImageLoading(k8sLoadImagesOpt.envPlatform)
+		cobra.CheckErr(err)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		cobra.CheckErr(loadImages(ctx, cmd, k8sLoadImagesOpt.envPlatform, k8sLoadImagesOpt.envName, k8sLoadImagesOpt.images))
 		return nil
 	},
 }
 
 type exportKubeconfigOptions struct {
 	envOptions
+	stateOptions
 	kubeconfigOptions
 }
 
@@ -97,7 +430,10 @@ var k8sExportKubeConfigCmd = &cobra.Command{
 	Use:   "export-kubeconfig",
 	Short: "export kubeconfig for a created cluster",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		err := validatePlatformName(k8sExportKubeConfigOpt.envPlatform)
+		_, err := resolveEnvFromState(&k8sExportKubeConfigOpt.envOptions, k8sExportKubeConfigOpt.stateOptions)
+		cobra.CheckErr(err)
+
+		err = validatePlatformName(k8sExportKubeConfigOpt.envPlatform)
 		cobra.CheckErr(err)
 		return nil
 	},
@@ -105,42 +441,247 @@ var k8sExportKubeConfigCmd = &cobra.Command{
 		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
 		defer cancel()
 
-		_, err := cluster_providers.GetBuilder(k8sExportKubeConfigOpt.envPlatform, cmd, k8sExportKubeConfigOpt.envName)
+		existingCls, err := cluster_providers.NewClusterFromExisting(ctx, k8sExportKubeConfigOpt.envPlatform, k8sExportKubeConfigOpt.envName,
+			k8sExportKubeConfigOpt.clusterOptions())
 		cobra.CheckErr(err)
 
-		existingCls, err := cluster_providers.NewClusterFromExisting(k8sExportKubeConfigOpt.envPlatform, ctx, cmd, k8sExportKubeConfigOpt.envName)
+		kubeconfig := cluster_providers.KubeconfigRestConfig(k8sExportKubeConfigOpt.envPlatform, k8sExportKubeConfigOpt.envName, existingCls,
+			k8sExportKubeConfigOpt.clusterOptions())
+		cobra.CheckErr(utils.WriteKubeconfig(k8sExportKubeConfigOpt.envName, cmd, kubeconfig, k8sExportKubeConfigOpt.kubeconfigOutputFile))
+		return nil
+	},
+}
+
+type k8sTokenOptions struct {
+	envOptions
+}
+
+var k8sTokenOpt = k8sTokenOptions{}
+var k8sTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "print a token to access a created cluster with, in the format of a kubectl exec credential",
+	Long: "Print a token to access a created cluster with, in the format of a kubectl exec credential. " +
+		"The kubeconfig exported for a cluster authenticating with short-lived tokens runs this command to refresh its token.",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validatePlatformName(k8sTokenOpt.envPlatform)
 		cobra.CheckErr(err)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		provider, err := cluster_providers.GetProvider(k8sTokenOpt.envPlatform)
+		cobra.CheckErr(err)
+		tokenProvider, ok := provider.(cluster_providers.TokenProvider)
+		if !ok {
+			cobra.CheckErr(fmt.Errorf("the clusters of platform %s do not authenticate with tokens", k8sTokenOpt.envPlatform))
+		}
 
-		cobra.CheckErr(utils.WriteKubeconfig(k8sExportKubeConfigOpt.envName, cmd, existingCls.Config(), k8sExportKubeConfigOpt.kubeconfigOutputFile))
+		token, err := tokenProvider.Token(ctx, k8sTokenOpt.envName, k8sTokenOpt.clusterOptions())
+		cobra.CheckErr(err)
+
+		credential := clientauthenticationv1beta1.ExecCredential{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clientauthenticationv1beta1.SchemeGroupVersion.String(),
+				Kind:       "ExecCredential",
+			},
+			Status: &clientauthenticationv1beta1.ExecCredentialStatus{
+				Token:               token.AccessToken,
+				ExpirationTimestamp: &metav1.Time{Time: token.Expiry},
+			},
+		}
+		content, err := json.Marshal(credential)
+		cobra.CheckErr(err)
+		utils.CmdStdout(cmd, "%s\n", content)
 		return nil
 	},
 }
 
-var k8sCleanupOpt = envOptions{}
+type k8sCleanupOptions struct {
+	envOptions
+	stateOptions
+}
+
+var k8sCleanupOpt = k8sCleanupOptions{}
 var k8sCleanupCmd = &cobra.Command{
 	Use:   "cleanup",
 	Short: "cleanup the installed resources during the smoke tests",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		err := validatePlatformName(k8sCleanupOpt.envPlatform)
+		_, err := resolveEnvFromState(&k8sCleanupOpt.envOptions, k8sCleanupOpt.stateOptions)
+		cobra.CheckErr(err)
+
+		err = validatePlatformName(k8sCleanupOpt.envPlatform)
 		cobra.CheckErr(err)
 
+		// cleaning up an existing cluster removes Kuma from it, so it must never fall back to the current context
+		if k8sCleanupOpt.envPlatform == "existing" && k8sCleanupOpt.kubeconfig == "" && k8sCleanupOpt.kubeContext == "" {
+			cobra.CheckErr(fmt.Errorf("the kubeconfig or the context of existing environment %s is neither recorded in the state "+
+				"nor given by --existing-kubeconfig or --existing-context", k8sCleanupOpt.envName))
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+		defer cancel()
+
+		return cleanupEnvironment(ctx, cmd, k8sCleanupOpt.envPlatform, k8sCleanupOpt.envName, k8sCleanupOpt.clusterOptions())
+	},
+}
+
+func cleanupEnvironment(ctx context.Context, cmd *cobra.Command, platform, envName string, opts cluster_providers.Options) error {
+	existingCls, err := cluster_providers.NewClusterFromExisting(ctx, platform, envName, opts)
+	if err != nil {
+		provider, _ := cluster_providers.GetProvider(platform)
+		cleaner, ok := provider.(cluster_providers.LeftoverCleaner)
+		if !ok {
+			return err
+		}
+		utils.CmdStdErr(cmd, "cluster of environment %s is not available (%s), cleaning up the resources left\n", envName, err)
+		return cleaner.CleanupLeftovers(ctx, envName, opts)
+	}
+
+	utils.CmdStdErr(cmd, "cleaning up cluster of environment %s\n", envName)
+	return existingCls.Cleanup(ctx)
+}
+
+type k8sListOptions struct {
+	envPlatforms []string
+	region       string
+	output       string
+}
+
+var k8sListOpt = k8sListOptions{}
+var k8sListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the environments created by the smoke tests that still exist",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, platform := range k8sListOpt.envPlatforms {
+			err := validatePlatformName(platform)
+			cobra.CheckErr(err)
+		}
+
+		if k8sListOpt.output != "table" && k8sListOpt.output != "json" {
+			cobra.CheckErr(fmt.Errorf("unsupported output format: '%s'. supported formats are: table, json", k8sListOpt.output))
+		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
 		defer cancel()
 
-		_, err := cluster_providers.GetBuilder(k8sCleanupOpt.envPlatform, cmd, k8sCleanupOpt.envName)
+		envs, err := listEnvironments(ctx, cmd, k8sListOpt.envPlatforms, cluster_providers.Options{Region: k8sListOpt.region})
 		cobra.CheckErr(err)
 
-		existingCls, err := cluster_providers.NewClusterFromExisting(k8sCleanupOpt.envPlatform, ctx, cmd, k8sCleanupOpt.envName)
+		if k8sListOpt.output == "json" {
+			content, err := json.MarshalIndent(envs, "", "  ")
+			cobra.CheckErr(err)
+			utils.CmdStdout(cmd, "%s\n", content)
+			return nil
+		}
+
+		writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "NAME\tPLATFORM\tAGE\tKUBERNETES")
+		for _, env := range envs {
+			age := "<unknown>"
+			if !env.CreatedAt.IsZero() {
+				age = duration.HumanDuration(time.Since(env.CreatedAt))
+			}
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", env.Name, env.Platform, age, env.KubernetesVersion)
+		}
+		return writer.Flush()
+	},
+}
+
+type k8sReapOptions struct {
+	envPlatforms []string
+	region       string
+	olderThan    time.Duration
+	dryRun       bool
+}
+
+var k8sReapOpt = k8sReapOptions{}
+var k8sReapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "cleanup the environments created by the smoke tests that are older than a TTL, e.g. the ones leaked by cancelled jobs",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, platform := range k8sReapOpt.envPlatforms {
+			err := validatePlatformName(platform)
+			cobra.CheckErr(err)
+		}
+
+		if k8sReapOpt.olderThan <= 0 {
+			cobra.CheckErr(errors.New("--older-than must be a positive duration"))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listCtx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+		defer cancel()
+
+		opts := cluster_providers.Options{Region: k8sReapOpt.region}
+		envs, err := listEnvironments(listCtx, cmd, k8sReapOpt.envPlatforms, opts)
 		cobra.CheckErr(err)
 
-		utils.CmdStdErr(cmd, "cleaning up cluster of environment %s\n", k8sCleanupOpt.envName)
-		return existingCls.Cleanup(ctx)
+		var errs []error
+		for _, env := range envs {
+			if env.CreatedAt.IsZero() {
+				utils.CmdStdErr(cmd, "skipping environment %s on platform %s: its creation time is unknown\n", env.Name, env.Platform)
+				continue
+			}
+			age := time.Since(env.CreatedAt)
+			if age < k8sReapOpt.olderThan {
+				continue
+			}
+
+			if k8sReapOpt.dryRun {
+				utils.CmdStdout(cmd, "would cleanup environment %s on platform %s, created %s ago\n",
+					env.Name, env.Platform, duration.HumanDuration(age))
+				continue
+			}
+
+			utils.CmdStdout(cmd, "cleaning up environment %s on platform %s, created %s ago\n",
+				env.Name, env.Platform, duration.HumanDuration(age))
+			// a failing environment does not stop reaping the others
+			if err := reapEnvironment(cmd, env, opts); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cleanup environment %s on platform %s: %w", env.Name, env.Platform, err))
+			}
+		}
+		return errors.Join(errs...)
 	},
 }
 
+func reapEnvironment(cmd *cobra.Command, env cluster_providers.Environment, opts cluster_providers.Options) error {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+	defer cancel()
+
+	return cleanupEnvironment(ctx, cmd, env.Platform, env.Name, opts)
+}
+
+// listEnvironments lists the environments on the platforms. When no platform is specified, all the supported platforms
+// are listed and the ones that can't be accessed (e.g. missing credentials) are skipped with a warning.
+func listEnvironments(ctx context.Context, cmd *cobra.Command, platforms []string, opts cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	skipInaccessible := len(platforms) == 0
+	if skipInaccessible {
+		platforms = cluster_providers.SupportedProviderNames
+	}
+
+	envs := []cluster_providers.Environment{}
+	for _, platform := range platforms {
+		platformEnvs, err := cluster_providers.ListEnvironments(ctx, platform, opts)
+		if err != nil {
+			if !skipInaccessible {
+				return nil, fmt.Errorf("failed to list environments on platform %s: %w", platform, err)
+			}
+			utils.CmdStdErr(cmd, "skipping platform %s: %s\n", platform, err)
+			continue
+		}
+		envs = append(envs, platformEnvs...)
+	}
+	return envs, nil
+}
+
 func validatePlatformName(platform string) error {
 	if !slices.Contains(cluster_providers.SupportedProviderNames, platform) {
 		return fmt.Errorf("unsupported platform: '%s'. supported platforms are: %s",
@@ -157,27 +698,101 @@ var k8sCmd = &cobra.Command{
 	},
 }
 
+const loadImagesUsage = "The images to load into the nodes of kind or k3d clusters, either archives created by 'docker save' or " +
+	"references to images of the local docker daemon, e.g. kumahq/kuma-cp:dev,kumahq/kuma-dp:dev,kumahq/kuma-init:dev,kumahq/kuma-cni:dev"
+
+const ipFamilyUsage = "The IP family of the cluster networking (ipv4, ipv6, dual), kind supports all of them and EKS supports ipv4 and ipv6"
+
 func init() {
-	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.kubernetesVersion, "kubernetes-version", test.MaxSupportedKubernetesVer, "The version of Kubernetes to deploy")
+	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.kubernetesVersion, "kubernetes-version", test.MaxSupportedKubernetesVer, "The version of Kubernetes to deploy, only the minor version is honored on GKE and EKS")
+	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.ipFamily, "ip-family", "ipv4", ipFamilyUsage)
+	k8sDeployOpt.providerFlags = cluster_providers.AddProviderFlags(k8sDeployCmd.Flags())
 	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.kubeconfigOutputFile, "kubeconfig-output", "", "The file path used to write the generated kubeconfig")
+	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.stateOutputFile, "state-output", "", "The file path used to write the state of the created environment")
+	k8sDeployCmd.Flags().StringSliceVar(&k8sDeployOpt.loadImages, "load-images", nil, loadImagesUsage)
 	k8sDeployCmd.Flags().StringVar(&k8sDeployOpt.envPlatform, "env-platform", "kind",
 		fmt.Sprintf("The platform to deploy the environment on (%s)",
 			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(k8sDeployCmd, &k8sDeployOpt.region)
 	k8sCmd.AddCommand(k8sDeployCmd)
 
 	k8sExportKubeConfigCmd.Flags().StringVar(&k8sExportKubeConfigOpt.envName, "env", "", "name of the existing environment")
-	_ = k8sExportKubeConfigCmd.MarkFlagRequired("env")
+	k8sExportKubeConfigCmd.Flags().StringVar(&k8sExportKubeConfigOpt.stateFile, "state", "", "The state file written when deploying the environment, replaces --env and --env-platform")
 	k8sExportKubeConfigCmd.Flags().StringVar(&k8sExportKubeConfigOpt.envPlatform, "env-platform", "kind",
 		fmt.Sprintf("The platform that the environment was deployed on (%s)",
 			strings.Join(cluster_providers.SupportedProviderNames, ",")))
 	k8sExportKubeConfigCmd.Flags().StringVar(&k8sExportKubeConfigOpt.kubeconfigOutputFile, "kubeconfig-output", "", "The file path used to write the generated kubeconfig")
 	_ = k8sExportKubeConfigCmd.MarkFlagRequired("kubeconfig-output")
+	addRegionFlag(k8sExportKubeConfigCmd, &k8sExportKubeConfigOpt.region)
+	addExistingClusterFlags(k8sExportKubeConfigCmd, &k8sExportKubeConfigOpt.envOptions)
 	k8sCmd.AddCommand(k8sExportKubeConfigCmd)
 
+	k8sTokenCmd.Flags().StringVar(&k8sTokenOpt.envName, "env", "", "name of the existing environment")
+	k8sTokenCmd.Flags().StringVar(&k8sTokenOpt.envPlatform, "env-platform", "eks",
+		fmt.Sprintf("The platform that the environment was deployed on (%s)",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	_ = k8sTokenCmd.MarkFlagRequired("env")
+	addRegionFlag(k8sTokenCmd, &k8sTokenOpt.region)
+	k8sCmd.AddCommand(k8sTokenCmd)
+
+	k8sLoadImagesCmd.Flags().StringVar(&k8sLoadImagesOpt.envName, "env", "", "name of the existing environment")
+	k8sLoadImagesCmd.Flags().StringVar(&k8sLoadImagesOpt.stateFile, "state", "", "The state file written when deploying the environment, replaces --env and --env-platform")
+	k8sLoadImagesCmd.Flags().StringVar(&k8sLoadImagesOpt.envPlatform, "env-platform", "kind",
+		fmt.Sprintf("The platform that the environment was deployed on (%s)",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	k8sLoadImagesCmd.Flags().StringSliceVar(&k8sLoadImagesOpt.images, "images", nil, loadImagesUsage)
+	_ = k8sLoadImagesCmd.MarkFlagRequired("images")
+	k8sCmd.AddCommand(k8sLoadImagesCmd)
+
+	k8sListCmd.Flags().StringSliceVar(&k8sListOpt.envPlatforms, "env-platform", nil,
+		fmt.Sprintf("The platforms to list the environments on (%s), all of them are listed when not set",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(k8sListCmd, &k8sListOpt.region)
+	k8sListCmd.Flags().StringVarP(&k8sListOpt.output, "output", "o", "table", "The output format (table, json)")
+	k8sCmd.AddCommand(k8sListCmd)
+
+	k8sReapCmd.Flags().StringSliceVar(&k8sReapOpt.envPlatforms, "env-platform", nil,
+		fmt.Sprintf("The platforms to reap the environments on (%s), all of them are reaped when not set",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(k8sReapCmd, &k8sReapOpt.region)
+	k8sReapCmd.Flags().DurationVar(&k8sReapOpt.olderThan, "older-than", 6*time.Hour, "The age that environments are cleaned up after")
+	k8sReapCmd.Flags().BoolVar(&k8sReapOpt.dryRun, "dry-run", false, "Only print the environments that would be cleaned up")
+	k8sCmd.AddCommand(k8sReapCmd)
+
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.productName, "product", "kuma", "The product to test (kuma, kong-mesh)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.productVersion, "version", "", "The version of the product to test")
+	_ = k8sRunCmd.MarkFlagRequired("version")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.kumactlBin, "kumactl", "", "The path to kumactl of the tested version (fetched when not set)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.prevMinorVersion, "prev-minor-version", "", "The version of the previous minor release to upgrade from (resolved from --version when not set)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.prevMinorKumactl, "prev-minor-kumactl", "", "The path to kumactl of the previous minor release (fetched when not set)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.prevPatchVersion, "prev-patch-version", "", "The version of the previous patch release to upgrade from (resolved from --version when not set)")
+	addKnownReleasesFlags(k8sRunCmd, &k8sRunOpt.knownReleasesOptions)
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.prevPatchKumactl, "prev-patch-kumactl", "", "The path to kumactl of the previous patch release (fetched when not set)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.kubernetesVersion, "kubernetes-version", test.MaxSupportedKubernetesVer, "The version of Kubernetes to deploy, only the minor version is honored on GKE and EKS")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.ipFamily, "ip-family", "ipv4", ipFamilyUsage)
+	k8sRunOpt.providerFlags = cluster_providers.AddProviderFlags(k8sRunCmd.Flags())
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.envPlatform, "env-platform", "kind",
+		fmt.Sprintf("The platform to deploy the environment on (%s)",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(k8sRunCmd, &k8sRunOpt.region)
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.debugDir, "debug-dir", "build/debug-output", "The directory to write debug output into")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.jsonReportFile, "json-report", "", "The file path used to write the JSON report of the smoke tests")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.stateFile, "state", "",
+		"The state file of an environment deployed beforehand to run the smoke tests on, the environment is kept after the run")
+	k8sRunCmd.Flags().DurationVar(&k8sRunOpt.timeout, "timeout", 4*time.Hour, "The timeout of running the smoke tests")
+	addFetchFlags(k8sRunCmd, &k8sRunOpt.fetchOptions)
+	k8sRunCmd.Flags().BoolVar(&k8sRunOpt.keepEnv, "keep-env", false, "Do not cleanup the environment after running the smoke tests")
+	k8sRunCmd.Flags().StringSliceVar(&k8sRunOpt.loadImages, "load-images", nil, loadImagesUsage)
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.imageRegistry, "image-registry", "", "The registry of the images of the tested version, e.g. of the loaded images (the registry of the product when not set)")
+	k8sRunCmd.Flags().StringVar(&k8sRunOpt.imageTag, "image-tag", "", "The tag of the images of the tested version, e.g. of the loaded images (--version when not set)")
+	k8sCmd.AddCommand(k8sRunCmd)
+
 	k8sCleanupCmd.Flags().StringVar(&k8sCleanupOpt.envName, "env", "", "name of the existing environment")
-	_ = k8sCleanupCmd.MarkFlagRequired("env")
+	k8sCleanupCmd.Flags().StringVar(&k8sCleanupOpt.stateFile, "state", "", "The state file written when deploying the environment, replaces --env and --env-platform")
 	k8sCleanupCmd.Flags().StringVar(&k8sCleanupOpt.envPlatform, "env-platform", "kind",
 		fmt.Sprintf("The platform that the environment was deployed on (%s)",
 			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(k8sCleanupCmd, &k8sCleanupOpt.region)
+	addExistingClusterFlags(k8sCleanupCmd, &k8sCleanupOpt.envOptions)
 	k8sCmd.AddCommand(k8sCleanupCmd)
 }
@@ -10,6 +10,10 @@ import (
 
 func init() {
 	rootCmd.AddCommand(k8sCmd)
+	rootCmd.AddCommand(universalCmd)
+	rootCmd.AddCommand(multizoneCmd)
+	rootCmd.AddCommand(productCmd)
+	rootCmd.AddCommand(versionsCmd)
 }
 
 func main() {
@@ -1,3 +1,241 @@
 package main
 
-// todo: add multizone implementation
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/kumahq/kuma-smoke/test"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type multizoneDeployOptions struct {
+	k8sVersionOptions
+	envOptions
+	zones               int
+	kubeconfigOutputDir string
+	stateOutputFile     string
+}
+
+var multizoneDeployOpt = multizoneDeployOptions{}
+var multizoneDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "deploy a global cluster and multiple zone clusters that the multizone smoke tests will be running on",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// the multizone smoke tests send requests from the first zone to the last one
+		if multizoneDeployOpt.zones < 2 {
+			cobra.CheckErr(fmt.Errorf("at least 2 zones are required, got %d", multizoneDeployOpt.zones))
+		}
+
+		err := parseKubernetesVersion(cmd, &multizoneDeployOpt.k8sVersionOptions)
+		cobra.CheckErr(err)
+
+		err = validatePlatformName(multizoneDeployOpt.envPlatform)
+		cobra.CheckErr(err)
+		// the zones and the global control plane would all be installed on the same cluster
+		if multizoneDeployOpt.envPlatform == "existing" {
+			cobra.CheckErr(errors.New("multizone environments can't be deployed on an existing cluster"))
+		}
+
+		err = multizoneDeployOpt.resolveClusterOptions(multizoneDeployOpt.envOptions)
+		cobra.CheckErr(err)
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		envName := utils.NewEnvName()
+		clusterNames := utils.MultizoneClusterNames(envName, multizoneDeployOpt.zones)
+		cobra.CheckErr(os.MkdirAll(multizoneDeployOpt.kubeconfigOutputDir, 0o755))
+
+		// the state is written before creating the clusters, so that an interrupted deployment can still be cleaned up
+		if multizoneDeployOpt.stateOutputFile != "" {
+			state := &cluster_providers.EnvironmentState{
+				Provider:          multizoneDeployOpt.envPlatform,
+				Name:              envName,
+				KubernetesVersion: multizoneDeployOpt.parsedK8sVersion.String(),
+				CreatedAt:         time.Now().UTC(),
+				Region:            multizoneDeployOpt.region,
+				Zones:             multizoneDeployOpt.zones,
+			}
+			cobra.CheckErr(state.Write(multizoneDeployOpt.stateOutputFile))
+		}
+
+		// clusters are created in parallel, otherwise creating them on cloud platforms could take hours
+		var wg sync.WaitGroup
+		var l sync.Mutex
+		var deployErrs []error
+		for _, clusterName := range clusterNames {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				// kind clusters are all attached to the "kind" docker network and k3d clusters to a shared one as well,
+				// so zones are able to reach the global control plane through node ports and no load balancer is needed.
+				// metallb would also assign overlapping address pools to the clusters sharing the network.
+				env, err := deployEnvironment(ctx, cmd, multizoneDeployOpt.envPlatform, clusterName, multizoneDeployOpt.clusterOpts, false)
+				if err == nil {
+					kubeconfigFile := filepath.Join(multizoneDeployOpt.kubeconfigOutputDir, multizoneKubeconfigName(envName, clusterName))
+					kubeconfig := cluster_providers.KubeconfigRestConfig(multizoneDeployOpt.envPlatform, clusterName, env.Cluster(), multizoneDeployOpt.clusterOpts)
+					err = utils.WriteKubeconfig(clusterName, cmd, kubeconfig, kubeconfigFile)
+				}
+
+				if err != nil {
+					l.Lock()
+					deployErrs = append(deployErrs, fmt.Errorf("failed to deploy cluster %s: %w", clusterName, err))
+					l.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(deployErrs) > 0 {
+			utils.CmdStdErr(cmd, "failed to deploy multizone environment %s, cleaning up all of its clusters\n", envName)
+			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+			defer cleanupCancel()
+			cleanupErr := cleanupMultizone(cleanupCtx, cmd, multizoneDeployOpt.envPlatform, envName,
+				multizoneDeployOpt.zones, multizoneDeployOpt.clusterOpts)
+			// the state file is kept when the cleanup fails, so that the cleanup can be retried with it
+			if cleanupErr == nil && multizoneDeployOpt.stateOutputFile != "" {
+				_ = os.Remove(multizoneDeployOpt.stateOutputFile)
+			}
+			return errors.Join(append(deployErrs, cleanupErr)...)
+		}
+
+		utils.CmdStdErr(cmd, "multizone environment %s was created successfully!\n", envName)
+		utils.CmdStdout(cmd, "%s", envName)
+		return nil
+	},
+}
+
+type multizoneCleanupOptions struct {
+	envOptions
+	stateOptions
+	zones int
+}
+
+var multizoneCleanupOpt = multizoneCleanupOptions{}
+var multizoneCleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "cleanup the global cluster and all the zone clusters of a multizone environment",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		envState, err := resolveEnvFromState(&multizoneCleanupOpt.envOptions, multizoneCleanupOpt.stateOptions)
+		cobra.CheckErr(err)
+		if envState != nil {
+			if envState.Zones == 0 {
+				cobra.CheckErr(fmt.Errorf("state file %s does not record a multizone environment", multizoneCleanupOpt.stateFile))
+			}
+			multizoneCleanupOpt.zones = envState.Zones
+		}
+
+		err = validatePlatformName(multizoneCleanupOpt.envPlatform)
+		cobra.CheckErr(err)
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+		defer cancel()
+
+		return cleanupMultizone(ctx, cmd, multizoneCleanupOpt.envPlatform, multizoneCleanupOpt.envName,
+			multizoneCleanupOpt.zones, multizoneCleanupOpt.clusterOptions())
+	},
+}
+
+// cleanupMultizone deletes all the clusters of a multizone environment. Failing to delete one of the
+// clusters does not stop the others from being deleted, and all the failures are returned together.
+// When the number of zones is unknown, the clusters are found on the platform by the name of the environment.
+func cleanupMultizone(ctx context.Context, cmd *cobra.Command, platform, envName string, zones int, opts cluster_providers.Options) error {
+	clusterNames := utils.MultizoneClusterNames(envName, zones)
+	if zones == 0 {
+		var err error
+		clusterNames, err = findMultizoneClusters(ctx, platform, envName, opts)
+		if err != nil {
+			return err
+		}
+		if len(clusterNames) == 0 {
+			utils.CmdStdErr(cmd, "no cluster of environment %s was found on platform %s\n", envName, platform)
+			return nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	var l sync.Mutex
+	var cleanupErrs []error
+	for _, clusterName := range clusterNames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := cleanupEnvironment(ctx, cmd, platform, clusterName, opts); err != nil {
+				l.Lock()
+				cleanupErrs = append(cleanupErrs, fmt.Errorf("failed to cleanup cluster %s: %w", clusterName, err))
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(cleanupErrs...)
+}
+
+// findMultizoneClusters returns the names of the clusters of a multizone environment that exist on the platform
+func findMultizoneClusters(ctx context.Context, platform, envName string, opts cluster_providers.Options) ([]string, error) {
+	envs, err := cluster_providers.ListEnvironments(ctx, platform, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the clusters of environment %s: %w", envName, err)
+	}
+
+	var clusterNames []string
+	for _, env := range envs {
+		if env.Name == envName+"-"+utils.MultizoneGlobalSuffix || strings.HasPrefix(env.Name, envName+"-zone-") {
+			clusterNames = append(clusterNames, env.Name)
+		}
+	}
+	return clusterNames, nil
+}
+
+// multizoneKubeconfigName returns the name of the kubeconfig file of a cluster, e.g. "global.config" or "zone-1.config"
+func multizoneKubeconfigName(envName, clusterName string) string {
+	return strings.TrimPrefix(clusterName, envName+"-") + ".config"
+}
+
+var multizoneCmd = &cobra.Command{
+	Use:   "multizone",
+	Short: "Prepare and run multizone smoke tests for Kuma on Kubernetes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("must pass a subcommand")
+	},
+}
+
+func init() {
+	multizoneDeployCmd.Flags().StringVar(&multizoneDeployOpt.kubernetesVersion, "kubernetes-version", test.MaxSupportedKubernetesVer, "The version of Kubernetes to deploy, only the minor version is honored on GKE and EKS")
+	multizoneDeployCmd.Flags().StringVar(&multizoneDeployOpt.ipFamily, "ip-family", "ipv4", ipFamilyUsage)
+	multizoneDeployOpt.providerFlags = cluster_providers.AddProviderFlags(multizoneDeployCmd.Flags())
+	multizoneDeployCmd.Flags().IntVar(&multizoneDeployOpt.zones, "zones", 2, "The number of zone clusters to deploy, at least 2")
+	multizoneDeployCmd.Flags().StringVar(&multizoneDeployOpt.kubeconfigOutputDir, "kubeconfig-output-dir", "", "The directory used to write the kubeconfig of each generated cluster")
+	_ = multizoneDeployCmd.MarkFlagRequired("kubeconfig-output-dir")
+	multizoneDeployCmd.Flags().StringVar(&multizoneDeployOpt.stateOutputFile, "state-output", "", "The file path used to write the state of the created environment")
+	multizoneDeployCmd.Flags().StringVar(&multizoneDeployOpt.envPlatform, "env-platform", "kind",
+		fmt.Sprintf("The platform to deploy the environment on (%s)",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(multizoneDeployCmd, &multizoneDeployOpt.region)
+	multizoneCmd.AddCommand(multizoneDeployCmd)
+
+	multizoneCleanupCmd.Flags().StringVar(&multizoneCleanupOpt.envName, "env", "", "name of the existing multizone environment")
+	multizoneCleanupCmd.Flags().StringVar(&multizoneCleanupOpt.stateFile, "state", "", "The state file written when deploying the environment, replaces --env, --env-platform and --zones")
+	multizoneCleanupCmd.Flags().IntVar(&multizoneCleanupOpt.zones, "zones", 0, "The number of zone clusters that were deployed, the clusters are found by the name of the environment when not set")
+	multizoneCleanupCmd.Flags().StringVar(&multizoneCleanupOpt.envPlatform, "env-platform", "kind",
+		fmt.Sprintf("The platform that the environment was deployed on (%s)",
+			strings.Join(cluster_providers.SupportedProviderNames, ",")))
+	addRegionFlag(multizoneCleanupCmd, &multizoneCleanupOpt.region)
+	multizoneCmd.AddCommand(multizoneCleanupCmd)
+}
@@ -1,17 +1,130 @@
 package main
 
-import "github.com/blang/semver/v4"
+import (
+	"errors"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/spf13/cobra"
+	"maps"
+	"slices"
+	"strings"
+)
 
 type k8sVersionOptions struct {
 	kubernetesVersion string
 	parsedK8sVersion  semver.Version
+	ipFamily          string
+	// providerFlags are the flags contributed by the providers, e.g. --eks-node-type
+	providerFlags *cluster_providers.ProviderFlags
+	// clusterOpts are resolved from all the above options once the platform is known
+	clusterOpts cluster_providers.Options
+}
+
+// resolveClusterOptions resolves the options to create clusters of the environment with
+func (o *k8sVersionOptions) resolveClusterOptions(env envOptions) error {
+	ipFamily, err := cluster_providers.ParseIPFamily(o.ipFamily)
+	if err != nil {
+		return err
+	}
+
+	o.clusterOpts = env.clusterOptions()
+	o.clusterOpts.KubernetesVersion = o.parsedK8sVersion
+	o.clusterOpts.IPFamily = ipFamily
+	if o.providerFlags == nil {
+		return nil
+	}
+	return o.providerFlags.Apply(env.envPlatform, &o.clusterOpts)
 }
 
 type envOptions struct {
 	envName     string
 	envPlatform string
+	// region locates the clusters of the cloud platforms outside the region set by the environment variables
+	region string
+	// kubeconfig and kubeContext locate the cluster of the existing platform
+	kubeconfig  string
+	kubeContext string
+}
+
+// clusterOptions returns the options locating the clusters of the environment
+func (o envOptions) clusterOptions() cluster_providers.Options {
+	return cluster_providers.Options{Region: o.region, Kubeconfig: o.kubeconfig, KubeContext: o.kubeContext}
+}
+
+const regionUsage = "The region (the location on GKE and AKS) of the clusters, it overrides the region set by the environment variables of the platform"
+
+func addRegionFlag(cmd *cobra.Command, region *string) {
+	cmd.Flags().StringVar(region, "region", "", regionUsage)
+}
+
+// addExistingClusterFlags adds the flags locating an existing cluster to the commands that don't create clusters,
+// the commands creating clusters get them from the existing provider
+func addExistingClusterFlags(cmd *cobra.Command, env *envOptions) {
+	cmd.Flags().StringVar(&env.kubeconfig, "existing-kubeconfig", "", "The kubeconfig of the existing cluster, the default loading rules of kubectl are used when not set")
+	cmd.Flags().StringVar(&env.kubeContext, "existing-context", "", "The kubeconfig context of the existing cluster, the current context is used when not set")
+}
+
+// stateOptions replaces envOptions with the state file written when deploying the environment
+type stateOptions struct {
+	stateFile string
+}
+
+// resolveEnvFromState fills the name and the platform of the environment from the state file when it is provided,
+// the loaded state is returned for the callers needing more of it and is nil when no state file is provided
+func resolveEnvFromState(env *envOptions, state stateOptions) (*cluster_providers.EnvironmentState, error) {
+	if (env.envName == "") == (state.stateFile == "") {
+		return nil, errors.New("exactly one of --env and --state must be specified")
+	}
+	if state.stateFile == "" {
+		return nil, nil
+	}
+
+	envState, err := cluster_providers.LoadEnvironmentState(state.stateFile)
+	if err != nil {
+		return nil, err
+	}
+	env.restoreFromState(envState)
+	return envState, nil
+}
+
+// restoreFromState fills the options from the state of the environment, the options locating
+// the clusters are only replaced by the ones recorded in the state
+func (o *envOptions) restoreFromState(envState *cluster_providers.EnvironmentState) {
+	o.envName = envState.Name
+	o.envPlatform = envState.Provider
+
+	stateOpts := envState.ClusterOptions()
+	if stateOpts.Region != "" {
+		o.region = stateOpts.Region
+	}
+	if stateOpts.Kubeconfig != "" {
+		o.kubeconfig = stateOpts.Kubeconfig
+	}
+	if stateOpts.KubeContext != "" {
+		o.kubeContext = stateOpts.KubeContext
+	}
 }
 
 type kubeconfigOptions struct {
 	kubeconfigOutputFile string
 }
+
+type productOptions struct {
+	productName    string
+	productVersion string
+}
+
+// productImageRegistries maps the supported products to the registries where their images are published
+var productImageRegistries = map[string]string{
+	"kuma":      "kumahq",
+	"kong-mesh": "kong",
+}
+
+func validateProductName(product string) error {
+	if _, ok := productImageRegistries[product]; !ok {
+		names := slices.Sorted(maps.Keys(productImageRegistries))
+		return fmt.Errorf("unsupported product: '%s'. supported products are: %s", product, strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/kumahq/kuma-smoke/pkg/product"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/spf13/cobra"
+	"path/filepath"
+)
+
+type fetchOptions struct {
+	cacheDir   string
+	archiveDir string
+}
+
+type productFetchOptions struct {
+	fetchOptions
+	productName string
+	versions    []string
+}
+
+var productFetchOpt = productFetchOptions{}
+var productFetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "fetch kumactl of product versions into the cache directory and print their paths",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validateProductName(productFetchOpt.productName)
+		cobra.CheckErr(err)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		for _, version := range productFetchOpt.versions {
+			kumactlPath, err := fetchKumactl(ctx, cmd, productFetchOpt.fetchOptions, productFetchOpt.productName, version)
+			cobra.CheckErr(err)
+			utils.CmdStdout(cmd, "%s\n", kumactlPath)
+		}
+		return nil
+	},
+}
+
+func fetchKumactl(ctx context.Context, cmd *cobra.Command, opts fetchOptions, productName, version string) (string, error) {
+	fetcher := &product.Fetcher{
+		CacheDir:   opts.cacheDir,
+		ArchiveDir: opts.archiveDir,
+		Warnf: func(format string, args ...interface{}) {
+			utils.CmdStdErr(cmd, "Warning: "+format, args...)
+		},
+	}
+	release := product.NewRelease(productName, version)
+
+	utils.CmdStdErr(cmd, "fetching kumactl of %s\n", release.Name())
+	kumactlPath, err := fetcher.Fetch(ctx, release)
+	if err != nil {
+		return "", err
+	}
+	// the path is passed to the test framework which may run in another working directory
+	return filepath.Abs(kumactlPath)
+}
+
+func addFetchFlags(cmd *cobra.Command, opts *fetchOptions) {
+	cmd.Flags().StringVar(&opts.cacheDir, "cache-dir", "build", "The directory that kumactl of each version is unpacked into")
+	cmd.Flags().StringVar(&opts.archiveDir, "archive-dir", "", "A local directory holding the release archives, nothing is downloaded when it is set")
+}
+
+var productCmd = &cobra.Command{
+	Use:   "product",
+	Short: "Manage the released binaries of the products under test",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("must pass a subcommand")
+	},
+}
+
+func init() {
+	productFetchCmd.Flags().StringVar(&productFetchOpt.productName, "product", "kuma", "The product to fetch (kuma, kong-mesh)")
+	productFetchCmd.Flags().StringSliceVar(&productFetchOpt.versions, "version", nil, "The versions of the product to fetch, can be repeated")
+	_ = productFetchCmd.MarkFlagRequired("version")
+	addFetchFlags(productFetchCmd, &productFetchOpt.fetchOptions)
+	productCmd.AddCommand(productFetchCmd)
+}
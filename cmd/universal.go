@@ -1,3 +1,208 @@
 package main
 
-// todo: add universal implementation
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/universal"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type universalDeployOptions struct {
+	productOptions
+	stateOutputFile string
+}
+
+var universalDeployOpt = universalDeployOptions{}
+var universalDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "deploy a Universal control plane in a docker container that the smoke tests will be running on",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validateProductName(universalDeployOpt.productName)
+		cobra.CheckErr(err)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		state, err := deployUniversal(ctx, cmd, universalDeployOpt.productOptions)
+		cobra.CheckErr(err)
+
+		cobra.CheckErr(state.Write(universalDeployOpt.stateOutputFile))
+		utils.CmdStdout(cmd, "%s", state.Name)
+		return nil
+	},
+}
+
+type universalRunOptions struct {
+	productOptions
+	fetchOptions
+	kumactlBin string
+	ginkgoBin  string
+	testRoot   string
+	debugDir   string
+	keepEnv    bool
+}
+
+var universalRunOpt = universalRunOptions{}
+var universalRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "deploy a Universal environment, run the smoke tests on it and clean it up",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		err := validateProductName(universalRunOpt.productName)
+		cobra.CheckErr(err)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+		defer cancel()
+
+		if universalRunOpt.kumactlBin == "" {
+			kumactlPath, err := fetchKumactl(ctx, cmd, universalRunOpt.fetchOptions, universalRunOpt.productName, universalRunOpt.productVersion)
+			cobra.CheckErr(err)
+			universalRunOpt.kumactlBin = kumactlPath
+		}
+
+		state, err := deployUniversal(ctx, cmd, universalRunOpt.productOptions)
+		cobra.CheckErr(err)
+		if !universalRunOpt.keepEnv {
+			defer func() {
+				cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+				defer cleanupCancel()
+
+				utils.CmdStdErr(cmd, "cleaning up containers of environment %s\n", state.Name)
+				if err := universal.Cleanup(cleanupCtx, state.Name); err != nil {
+					utils.CmdStdErr(cmd, "failed to clean up environment %s: %v\n", state.Name, err)
+				}
+			}()
+		}
+
+		// errors are returned from here on, cobra.CheckErr would exit without cleaning up the environment
+		stateFile, err := os.CreateTemp("", "kuma-smoke-universal-state")
+		if err != nil {
+			return err
+		}
+		_ = stateFile.Close()
+		defer os.Remove(stateFile.Name())
+		if err := state.Write(stateFile.Name()); err != nil {
+			return err
+		}
+
+		return runUniversalSuite(cmd, stateFile.Name())
+	},
+}
+
+type universalCleanupOptions struct {
+	envName string
+	stateOptions
+}
+
+var universalCleanupOpt = universalCleanupOptions{}
+var universalCleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "cleanup the containers created for a Universal environment",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if (universalCleanupOpt.envName == "") == (universalCleanupOpt.stateFile == "") {
+			cobra.CheckErr(errors.New("exactly one of --env and --state must be specified"))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), utils.CleanupTimeout)
+		defer cancel()
+
+		envName := universalCleanupOpt.envName
+		if universalCleanupOpt.stateFile != "" {
+			state, err := universal.LoadState(universalCleanupOpt.stateFile)
+			cobra.CheckErr(err)
+			envName = state.Name
+		}
+
+		utils.CmdStdErr(cmd, "cleaning up containers of environment %s\n", envName)
+		return universal.Cleanup(ctx, envName)
+	},
+}
+
+func deployUniversal(ctx context.Context, cmd *cobra.Command, opts productOptions) (*universal.State, error) {
+	deployOpts := universal.DeployOptions{
+		Name:  utils.NewEnvName(),
+		Image: universal.ImageRef(productImageRegistries[opts.productName], opts.productVersion),
+	}
+
+	utils.CmdStdErr(cmd, "starting control plane of environment %s using image %s\n", deployOpts.Name, deployOpts.Image)
+	state, err := universal.Deploy(ctx, deployOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.CmdStdErr(cmd, "environment %s was created successfully!\n", state.Name)
+	return state, nil
+}
+
+func runUniversalSuite(cmd *cobra.Command, stateFile string) error {
+	testRoot, err := filepath.Abs(universalRunOpt.testRoot)
+	if err != nil {
+		return err
+	}
+	debugDir := universalRunOpt.debugDir
+	if debugDir == "" {
+		debugDir = filepath.Join(testRoot, "build", "debug-output")
+	}
+	if err := os.MkdirAll(debugDir, 0o755); err != nil {
+		return err
+	}
+
+	ginkgo := exec.Command(universalRunOpt.ginkgoBin, "-v", "--timeout=1h", "./test/universal/...")
+	ginkgo.Dir = testRoot
+	ginkgo.Stdout = cmd.OutOrStdout()
+	ginkgo.Stderr = cmd.OutOrStderr()
+	ginkgo.Env = append(os.Environ(),
+		"TEST_ROOT="+testRoot,
+		"E2E_CONFIG_FILE="+filepath.Join(testRoot, "test", "cfg", fmt.Sprintf("%s-universal.yaml", universalRunOpt.productName)),
+		"KUMA_DEBUG_DIR="+debugDir,
+		"KUMACTLBIN="+universalRunOpt.kumactlBin,
+		"KUMA_GLOBAL_IMAGE_TAG="+universalRunOpt.productVersion,
+		"SMOKE_ENV_STATE="+stateFile,
+	)
+
+	utils.CmdStdErr(cmd, "running smoke tests: %s\n", strings.Join(ginkgo.Args, " "))
+	return ginkgo.Run()
+}
+
+var universalCmd = &cobra.Command{
+	Use:   "universal",
+	Short: "Prepare and run smoke tests for Kuma on Universal (docker containers)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("must pass a subcommand")
+	},
+}
+
+func init() {
+	universalDeployCmd.Flags().StringVar(&universalDeployOpt.productName, "product", "kuma", "The product to deploy (kuma, kong-mesh)")
+	universalDeployCmd.Flags().StringVar(&universalDeployOpt.productVersion, "version", "", "The version of the product to deploy")
+	_ = universalDeployCmd.MarkFlagRequired("version")
+	universalDeployCmd.Flags().StringVar(&universalDeployOpt.stateOutputFile, "state-output", "", "The file path used to write the state of the created environment")
+	_ = universalDeployCmd.MarkFlagRequired("state-output")
+	universalCmd.AddCommand(universalDeployCmd)
+
+	universalRunCmd.Flags().StringVar(&universalRunOpt.productName, "product", "kuma", "The product to test (kuma, kong-mesh)")
+	universalRunCmd.Flags().StringVar(&universalRunOpt.productVersion, "version", "", "The version of the product to test")
+	_ = universalRunCmd.MarkFlagRequired("version")
+	universalRunCmd.Flags().StringVar(&universalRunOpt.kumactlBin, "kumactl", "", "The path to kumactl of the tested version (fetched when not set)")
+	addFetchFlags(universalRunCmd, &universalRunOpt.fetchOptions)
+	universalRunCmd.Flags().StringVar(&universalRunOpt.ginkgoBin, "ginkgo", "ginkgo", "The path to the ginkgo binary used to run the smoke tests")
+	universalRunCmd.Flags().StringVar(&universalRunOpt.testRoot, "test-root", ".", "The root directory of the kuma-smoke repository")
+	universalRunCmd.Flags().StringVar(&universalRunOpt.debugDir, "debug-dir", "", "The directory to write debug output into (defaults to build/debug-output under the test root)")
+	universalRunCmd.Flags().BoolVar(&universalRunOpt.keepEnv, "keep-env", false, "Do not cleanup the environment after running the smoke tests")
+	universalCmd.AddCommand(universalRunCmd)
+
+	universalCleanupCmd.Flags().StringVar(&universalCleanupOpt.envName, "env", "", "name of the existing environment")
+	universalCleanupCmd.Flags().StringVar(&universalCleanupOpt.stateFile, "state", "", "The state file written when deploying the environment")
+	universalCmd.AddCommand(universalCleanupCmd)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/kumahq/kuma-smoke/pkg/versions"
+	"github.com/spf13/cobra"
+)
+
+type knownReleasesOptions struct {
+	knownReleases     []string
+	knownReleasesFile string
+}
+
+type versionsResolveOptions struct {
+	knownReleasesOptions
+	field string
+}
+
+var versionsResolveOpt = versionsResolveOptions{}
+var versionsResolveCmd = &cobra.Command{
+	Use:   "resolve <version>",
+	Short: "resolve the previous minor and previous patch versions that the upgrade tests of a version start from",
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		switch versionsResolveOpt.field {
+		case "", "prev-minor", "prev-patch":
+		default:
+			cobra.CheckErr(fmt.Errorf("unsupported field: '%s'. supported fields are: prev-minor, prev-patch", versionsResolveOpt.field))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		upgradeFrom, err := resolveUpgradeFrom(args[0], versionsResolveOpt.knownReleasesOptions)
+		cobra.CheckErr(err)
+
+		prevPatch := ""
+		if upgradeFrom.PrevPatch != nil {
+			prevPatch = upgradeFrom.PrevPatch.String()
+		}
+
+		switch versionsResolveOpt.field {
+		case "prev-minor":
+			utils.CmdStdout(cmd, "%s\n", upgradeFrom.PrevMinor)
+		case "prev-patch":
+			utils.CmdStdout(cmd, "%s\n", prevPatch)
+		default:
+			utils.CmdStdout(cmd, "prev-minor: %s\n", upgradeFrom.PrevMinor)
+			utils.CmdStdout(cmd, "prev-patch: %s\n", prevPatch)
+		}
+		return nil
+	},
+}
+
+func resolveUpgradeFrom(version string, opts knownReleasesOptions) (versions.UpgradeFrom, error) {
+	knownReleases, err := versions.LoadKnownReleases(opts.knownReleases, opts.knownReleasesFile)
+	if err != nil {
+		return versions.UpgradeFrom{}, err
+	}
+	return versions.Resolve(version, knownReleases)
+}
+
+func addKnownReleasesFlags(cmd *cobra.Command, opts *knownReleasesOptions) {
+	cmd.Flags().StringSliceVar(&opts.knownReleases, "known-releases", nil, "The released versions of the product, used to resolve the versions to upgrade from")
+	cmd.Flags().StringVar(&opts.knownReleasesFile, "known-releases-file", "", "A file listing the released versions of the product, one version per line")
+}
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "Resolve the product versions used by the smoke tests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("must pass a subcommand")
+	},
+}
+
+func init() {
+	versionsResolveCmd.Flags().StringVar(&versionsResolveOpt.field, "field", "", "Only print the value of one field (prev-minor, prev-patch), an empty line is printed for a missing previous patch")
+	addKnownReleasesFlags(versionsResolveCmd, &versionsResolveOpt.knownReleasesOptions)
+	versionsCmd.AddCommand(versionsResolveCmd)
+}
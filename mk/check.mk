@@ -6,6 +6,10 @@ tidy:
 fmt:
 	go fmt ./...
 
+.PHONY: test
+test:
+	go test ./pkg/...
+
 .PHONY: check
 check: tidy fmt
 	# fail if Git working tree is dirty or there are untracked files
@@ -0,0 +1,18 @@
+#!/usr/bin/env bash
+
+set -e
+
+OUTPUT_DIR=$1/bin
+VERSION="5.7.4"
+K3D=${OUTPUT_DIR}/k3d
+if [ -e "$K3D" ] && [ "$($K3D version | head -1 | cut -d ' ' -f3)" == v${VERSION} ]; then
+  echo "$($K3D version | head -1) is already installed at ${OUTPUT_DIR}" ;
+  exit
+fi
+echo "Installing k3d ${VERSION} ..."
+set -x
+# see https://k3d.io/stable/#installation
+curl --location --fail -s -o "${K3D}" https://github.com/k3d-io/k3d/releases/download/v${VERSION}/k3d-"${OS}"-"${ARCH}"
+chmod +x "${K3D}"
+set +x
+echo "k3d $VERSION has been installed at $OUTPUT_DIR"
@@ -1,63 +1,118 @@
 SMOKE_PRODUCT_NAME ?= kuma
 SMOKE_PRODUCT_VERSION ?= 2.9.2
 SMOKE_ENV_TYPE ?= kind
+SMOKE_ZONES ?= 2
+# the region (the location on GKE and AKS) of the clusters on cloud platforms, the default of the platform when not set
+SMOKE_REGION ?=
+# extra flags of the deploy commands, e.g. "--kind-registry-mirrors" to pull images through local registry mirrors
+SMOKE_DEPLOY_FLAGS ?=
 
-CLEAN_VERSION := $(word 1,$(subst -, ,$(SMOKE_PRODUCT_VERSION)))
-MAJOR := $(word 1,$(subst ., ,$(CLEAN_VERSION)))
-MINOR := $(word 2,$(subst ., ,$(CLEAN_VERSION)))
-PATCH := $(word 3,$(subst ., ,$(CLEAN_VERSION)))
+# set SMOKE_KNOWN_RELEASES_FILE to a file listing the released versions (one per line) to resolve
+# the previous versions from actual releases, it is required for major versions and development builds
+SMOKE_KNOWN_RELEASES_FILE ?=
+RESOLVE_VERSIONS = $(TOP)/build/kuma-smoke versions resolve $(SMOKE_PRODUCT_VERSION) \
+	$(if $(SMOKE_KNOWN_RELEASES_FILE),--known-releases-file $(SMOKE_KNOWN_RELEASES_FILE))
 
-PREV_MINOR := $(if $(filter-out 1,$(MAJOR)),$(shell echo $$(($(MINOR)-1))),$(MINOR))
-SMOKE_PRODUCT_VERSION_PREV_MINOR := $(MAJOR).$(PREV_MINOR).0
+ifndef SMOKE_PRODUCT_VERSION_PREV_MINOR
+SMOKE_PRODUCT_VERSION_PREV_MINOR := $(shell [ -x $(TOP)/build/kuma-smoke ] && $(RESOLVE_VERSIONS) --field prev-minor)
+endif
 KUMACTLBIN_PREV_MINOR = $(TOP)/build/$(SMOKE_PRODUCT_NAME)-$(SMOKE_PRODUCT_VERSION_PREV_MINOR)/bin/kumactl
 
-PREV_PATCH := $(if $(filter-out 0,$(PATCH)),$(shell echo $$(($(PATCH)-1))),0)
-IS_PREVIEW := $(findstring preview,$(SMOKE_PRODUCT_VERSION))
-SMOKE_PRODUCT_VERSION_PREV_PATCH := $(if $(and $(IS_PREVIEW),$(filter 0,$(PATCH))),$(SMOKE_PRODUCT_VERSION),$(MAJOR).$(MINOR).$(PREV_PATCH))
-KUMACTLBIN_PREV_PATCH = $(TOP)/build/$(SMOKE_PRODUCT_NAME)-$(SMOKE_PRODUCT_VERSION_PREV_PATCH)/bin/kumactl
+# the previous patch version is empty when the tested version is the first release of its minor version
+ifndef SMOKE_PRODUCT_VERSION_PREV_PATCH
+SMOKE_PRODUCT_VERSION_PREV_PATCH := $(shell [ -x $(TOP)/build/kuma-smoke ] && $(RESOLVE_VERSIONS) --field prev-patch)
+endif
+KUMACTLBIN_PREV_PATCH = $(if $(SMOKE_PRODUCT_VERSION_PREV_PATCH),$(TOP)/build/$(SMOKE_PRODUCT_NAME)-$(SMOKE_PRODUCT_VERSION_PREV_PATCH)/bin/kumactl)
 
 KUMACTLBIN = $(TOP)/build/$(SMOKE_PRODUCT_NAME)-$(SMOKE_PRODUCT_VERSION)/bin/kumactl
 
-E2E_ENV_VARS += KUMA_K8S_TYPE=kind
+# the Kuma test framework only distinguishes k3d from kind, the cloud platforms are configured by the config files
+E2E_ENV_VARS += KUMA_K8S_TYPE=$(if $(filter k3d,$(SMOKE_ENV_TYPE)),k3d,kind)
 E2E_ENV_VARS += TEST_ROOT="$(TOP)"
 E2E_ENV_VARS += E2E_CONFIG_FILE="$(TOP)/test/cfg/$(SMOKE_PRODUCT_NAME)-$(SMOKE_ENV_TYPE).yaml"
 E2E_ENV_VARS += KUMA_DEBUG_DIR="$(TOP)/build/debug-output"
 E2E_ENV_VARS += KUMACTLBIN="$(KUMACTLBIN)"
+# the kubeconfigs of the clusters authenticating with short-lived tokens run kuma-smoke, which is not in the PATH of ginkgo
+E2E_ENV_VARS += KUMA_SMOKE_BIN="$(TOP)/build/kuma-smoke"
 E2E_ENV_VARS += KUMA_GLOBAL_IMAGE_TAG="$(SMOKE_PRODUCT_VERSION)"
 E2E_ENV_VARS += KUMACTLBIN_PREV_MINOR="$(KUMACTLBIN_PREV_MINOR)"
 E2E_ENV_VARS += SMOKE_PRODUCT_VERSION_PREV_MINOR="$(SMOKE_PRODUCT_VERSION_PREV_MINOR)"
 E2E_ENV_VARS += KUMACTLBIN_PREV_PATCH="$(KUMACTLBIN_PREV_PATCH)"
 E2E_ENV_VARS += SMOKE_PRODUCT_VERSION_PREV_PATCH="$(SMOKE_PRODUCT_VERSION_PREV_PATCH)"
 
-INSTALLER_URL=https://kuma.io/installer.sh
-ifeq ($(SMOKE_PRODUCT_NAME),kong-mesh)
-	INSTALLER_URL = https://docs.konghq.com/mesh/installer.sh
-endif
+# set SMOKE_LOAD_IMAGES to the images (archives or references of the local docker daemon, separated by commas) to load into
+# kind or k3d clusters, and SMOKE_IMAGE_REGISTRY and SMOKE_IMAGE_TAG to test them in place of the published images
+SMOKE_LOAD_IMAGES ?=
+SMOKE_IMAGE_REGISTRY ?=
+SMOKE_IMAGE_TAG ?=
+E2E_ENV_VARS += SMOKE_IMAGE_REGISTRY="$(SMOKE_IMAGE_REGISTRY)"
+E2E_ENV_VARS += SMOKE_IMAGE_TAG="$(SMOKE_IMAGE_TAG)"
+
+# set SMOKE_ARCHIVE_DIR to a directory holding the release archives to fetch kumactl without network access
+SMOKE_ARCHIVE_DIR ?=
 
 .PHONY: fetch-product
 fetch-product:
-	@mkdir -p build
-	@[ -f $(KUMACTLBIN) ] || (cd build && echo "Downloading installer of $(SMOKE_PRODUCT_NAME) (version $(SMOKE_PRODUCT_VERSION))" && curl -L $(INSTALLER_URL) | VERSION=$(SMOKE_PRODUCT_VERSION) sh -)
-	@[ -f $(KUMACTLBIN_PREV_MINOR) ] || (cd build && echo "Downloading installer of $(SMOKE_PRODUCT_NAME) (version $(SMOKE_PRODUCT_VERSION_PREV_MINOR))" && curl -L $(INSTALLER_URL) | VERSION=$(SMOKE_PRODUCT_VERSION_PREV_MINOR) sh -)
-	@[ -f $(KUMACTLBIN_PREV_PATCH) ] || (cd build && echo "Downloading installer of $(SMOKE_PRODUCT_NAME) (version $(SMOKE_PRODUCT_VERSION_PREV_PATCH))" && curl -L $(INSTALLER_URL) | VERSION=$(SMOKE_PRODUCT_VERSION_PREV_PATCH) sh -)
+	@[ -f $(TOP)/build/kuma-smoke ] || (echo "Please run 'make build' first" && exit 1)
+	@[ -n "$(SMOKE_PRODUCT_VERSION_PREV_MINOR)" ] || (echo "Failed to resolve the previous minor version of $(SMOKE_PRODUCT_VERSION)" && exit 1)
+	@$(TOP)/build/kuma-smoke product fetch --product $(SMOKE_PRODUCT_NAME) --cache-dir $(TOP)/build \
+		$(if $(SMOKE_ARCHIVE_DIR),--archive-dir $(SMOKE_ARCHIVE_DIR)) \
+		--version $(SMOKE_PRODUCT_VERSION) \
+		--version $(SMOKE_PRODUCT_VERSION_PREV_MINOR) \
+		$(if $(SMOKE_PRODUCT_VERSION_PREV_PATCH),--version $(SMOKE_PRODUCT_VERSION_PREV_PATCH)) > /dev/null
 
 .PHONY: deploy-kubernetes
 deploy-kubernetes:
 	@[ -f $(TOP)/build/kuma-smoke ] || (echo "Please run 'make build' first" && exit 1)
 	@mkdir -p $(TOP)/build/kubernetes
-	@$(TOP)/build/kuma-smoke kubernetes deploy --env-platform $(SMOKE_ENV_TYPE) --kubeconfig-output $(TOP)/build/kubernetes/cluster.config
+	@$(TOP)/build/kuma-smoke kubernetes deploy --env-platform $(SMOKE_ENV_TYPE) --kubeconfig-output $(TOP)/build/kubernetes/cluster.config \
+		--state-output $(TOP)/build/kubernetes/state.json $(if $(SMOKE_REGION),--region $(SMOKE_REGION)) $(SMOKE_DEPLOY_FLAGS) $(if $(SMOKE_LOAD_IMAGES),--load-images $(SMOKE_LOAD_IMAGES))
 
 .PHONY: cleanup-kubernetes
 cleanup-kubernetes:
-	$(eval ENV_NAME=$(shell kubectl --kubeconfig=$(TOP)/build/kubernetes/cluster.config config view -o jsonpath='{.clusters[0].name}'))
-	@if [ "$(ENV_NAME)" != "" ]; then \
-		$(TOP)/build/kuma-smoke kubernetes cleanup --env $(ENV_NAME) --env-platform $(SMOKE_ENV_TYPE) ; \
-		rm -f $(TOP)/build/kubernetes/cluster.config; \
+	@if [ -f $(TOP)/build/kubernetes/state.json ]; then \
+		$(TOP)/build/kuma-smoke kubernetes cleanup --state $(TOP)/build/kubernetes/state.json && \
+		rm -f $(TOP)/build/kubernetes/cluster.config $(TOP)/build/kubernetes/state.json; \
+	fi
+
+# cleans up the environments leaked by the runs that were cancelled before cleaning up
+SMOKE_REAP_OLDER_THAN ?= 6h
+.PHONY: reap-kubernetes
+reap-kubernetes:
+	@[ -f $(TOP)/build/kuma-smoke ] || (echo "Please run 'make build' first" && exit 1)
+	@$(TOP)/build/kuma-smoke kubernetes reap --env-platform $(SMOKE_ENV_TYPE) --older-than $(SMOKE_REAP_OLDER_THAN) \
+		$(if $(SMOKE_REGION),--region $(SMOKE_REGION))
+
+.PHONY: deploy-multizone
+deploy-multizone:
+	@[ -f $(TOP)/build/kuma-smoke ] || (echo "Please run 'make build' first" && exit 1)
+	@mkdir -p $(TOP)/build/multizone
+	@$(TOP)/build/kuma-smoke multizone deploy --zones $(SMOKE_ZONES) --env-platform $(SMOKE_ENV_TYPE) \
+		--kubeconfig-output-dir $(TOP)/build/multizone --state-output $(TOP)/build/multizone/state.json \
+		$(if $(SMOKE_REGION),--region $(SMOKE_REGION)) $(SMOKE_DEPLOY_FLAGS) > $(TOP)/build/multizone/env-name
+
+.PHONY: cleanup-multizone
+cleanup-multizone:
+	@if [ -f $(TOP)/build/multizone/state.json ]; then \
+		$(TOP)/build/kuma-smoke multizone cleanup --state $(TOP)/build/multizone/state.json && \
+		rm -rf $(TOP)/build/multizone; \
 	fi
 
 .PHONY: run
 run: fetch-product deploy-kubernetes
-	$(eval ENV_NAME=$(shell kubectl --kubeconfig=$(TOP)/build/kubernetes/cluster.config config view -o jsonpath='{.clusters[0].name}'))
 	mkdir -p $(TOP)/build/debug-output
-	$(E2E_ENV_VARS) SMOKE_ENV_TYPE=$(SMOKE_ENV_TYPE) SMOKE_ENV_NAME=$(ENV_NAME) $(GINKGO) -v --timeout=4h --json-report=raw-report.json ./test/...
+	$(E2E_ENV_VARS) SMOKE_ENV_STATE=$(TOP)/build/kubernetes/state.json $(GINKGO) -v --timeout=4h --json-report=raw-report.json ./test/kubernetes/...
 	$(MAKE) cleanup-kubernetes
+
+.PHONY: run-universal
+run-universal: fetch-product
+	@[ -f $(TOP)/build/kuma-smoke ] || (echo "Please run 'make build' first" && exit 1)
+	@$(TOP)/build/kuma-smoke universal run --product $(SMOKE_PRODUCT_NAME) --version $(SMOKE_PRODUCT_VERSION) \
+		--kumactl $(KUMACTLBIN) --ginkgo $(GINKGO) --test-root $(TOP) --debug-dir $(TOP)/build/debug-output
+
+.PHONY: run-multizone
+run-multizone: fetch-product deploy-multizone
+	$(eval ENV_NAME=$(shell cat $(TOP)/build/multizone/env-name))
+	mkdir -p $(TOP)/build/debug-output
+	$(E2E_ENV_VARS) SMOKE_ENV_TYPE=$(SMOKE_ENV_TYPE) SMOKE_ENV_NAME=$(ENV_NAME) SMOKE_ENV_REGION=$(SMOKE_REGION) SMOKE_ZONES=$(SMOKE_ZONES) $(GINKGO) -v --timeout=4h --json-report=raw-report.json ./test/multizone/...
+	$(MAKE) cleanup-multizone
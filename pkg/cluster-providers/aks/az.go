@@ -0,0 +1,82 @@
+package aks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// settings are read from the environment variables, the clusters are managed with the az CLI that is logged in
+type settings struct {
+	subscription  string
+	resourceGroup string
+	location      string
+}
+
+func (s settings) args(args ...string) []string {
+	args = append(args, "--resource-group", s.resourceGroup)
+	if s.subscription != "" {
+		args = append(args, "--subscription", s.subscription)
+	}
+	return args
+}
+
+// managedCluster is the subset of the output of "az aks show" used by the provider
+type managedCluster struct {
+	Name                     string            `json:"name"`
+	CurrentKubernetesVersion string            `json:"currentKubernetesVersion"`
+	ProvisioningState        string            `json:"provisioningState"`
+	Tags                     map[string]string `json:"tags"`
+	SystemData               struct {
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"systemData"`
+}
+
+func runAz(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "az", append(args, "--only-show-errors")...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run 'az %s': %s", strings.Join(args[:2], " "), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func runAzJSON(ctx context.Context, result interface{}, args ...string) error {
+	out, err := runAz(ctx, append(args, "--output", "json")...)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(json.Unmarshal(out, result), "failed to parse the output of 'az %s'", strings.Join(args[:2], " "))
+}
+
+// validateKubernetesVersion checks that AKS offers the minor version of Kubernetes in the location
+func validateKubernetesVersion(ctx context.Context, s settings, minor string) error {
+	var versions struct {
+		Values []struct {
+			Version string `json:"version"`
+		} `json:"values"`
+	}
+	args := []string{"aks", "get-versions", "--location", s.location}
+	if s.subscription != "" {
+		args = append(args, "--subscription", s.subscription)
+	}
+	if err := runAzJSON(ctx, &versions, args...); err != nil {
+		return err
+	}
+
+	var available []string
+	for _, v := range versions.Values {
+		if v.Version == minor {
+			return nil
+		}
+		available = append(available, v.Version)
+	}
+	return errors.Errorf("Kubernetes %s is not available on AKS in location %s, available versions are: %s",
+		minor, s.location, strings.Join(available, ", "))
+}
@@ -0,0 +1,102 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+)
+
+// Builder generates clusters.Cluster objects backed by AKS given
+// provided configuration options.
+type Builder struct {
+	Name string
+
+	settings       settings
+	clusterVersion *semver.Version
+	nodeVMSize     string
+	nodeCount      int
+	labels         map[string]string
+}
+
+const (
+	defaultNodeVMSize = "Standard_D16s_v5"
+	defaultNodeCount  = 1
+	// smokeEnvironmentTag is put on the AKS clusters created by kuma-smoke, its value is the name of the environment
+	smokeEnvironmentTag = "kuma-smoke-environment"
+)
+
+func newBuilder(s settings) *Builder {
+	return &Builder{
+		settings:   s,
+		nodeVMSize: defaultNodeVMSize,
+		nodeCount:  defaultNodeCount,
+	}
+}
+
+// WithClusterVersion configures the Kubernetes cluster version, only the minor version is honored
+// and AKS selects the latest patch of it.
+func (b *Builder) WithClusterVersion(version semver.Version) *Builder {
+	b.clusterVersion = &version
+	return b
+}
+
+func (b *Builder) WithNodeVMSize(vmSize string) *Builder {
+	b.nodeVMSize = vmSize
+	return b
+}
+
+func (b *Builder) WithNodeCount(count int) *Builder {
+	b.nodeCount = count
+	return b
+}
+
+// WithLabels adds tags that the created cluster is going to be tagged with.
+func (b *Builder) WithLabels(labels map[string]string) *Builder {
+	if b.labels == nil {
+		b.labels = map[string]string{}
+	}
+	for k, v := range labels {
+		b.labels[k] = v
+	}
+	return b
+}
+
+// Build creates and configures clients for an AKS-based Kubernetes clusters.Cluster.
+func (b *Builder) Build(ctx context.Context) (clusters.Cluster, error) {
+	args := []string{"aks", "create",
+		"--name", b.Name,
+		"--location", b.settings.location,
+		"--node-count", strconv.Itoa(b.nodeCount),
+		"--node-vm-size", b.nodeVMSize,
+		// Azure CNI is the network plugin that kuma-cni is chained to on AKS
+		"--network-plugin", "azure",
+		"--no-ssh-key",
+		"--tags", fmt.Sprintf("%s=%s", smokeEnvironmentTag, b.Name),
+	}
+	keys := make([]string, 0, len(b.labels))
+	for k := range b.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", k, b.labels[k]))
+	}
+
+	if b.clusterVersion != nil {
+		minor := fmt.Sprintf("%d.%d", b.clusterVersion.Major, b.clusterVersion.Minor)
+		if err := validateKubernetesVersion(ctx, b.settings, minor); err != nil {
+			return nil, err
+		}
+		args = append(args, "--kubernetes-version", minor)
+	}
+
+	if _, err := runAz(ctx, b.settings.args(args...)...); err != nil {
+		return nil, errors.Wrapf(err, "failed to create AKS cluster %s", b.Name)
+	}
+
+	return newFromExisting(ctx, b.settings, b.Name)
+}
@@ -0,0 +1,157 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"strings"
+	"sync"
+)
+
+const aksClusterType clusters.Type = "aks"
+
+// Cluster is a clusters.Cluster implementation backed by Azure Kubernetes Service (AKS)
+type Cluster struct {
+	name     string
+	settings settings
+	client   *kubernetes.Clientset
+	cfg      *rest.Config
+	addons   clusters.Addons
+	l        *sync.RWMutex
+}
+
+// newFromExisting provides a new clusters.Cluster backed by an existing AKS cluster,
+// the credentials of the cluster are fetched with the az CLI
+func newFromExisting(ctx context.Context, s settings, name string) (*Cluster, error) {
+	kubeconfig, err := runAz(ctx, s.args("aks", "get-credentials", "--name", name, "--file", "-")...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get credentials of AKS cluster %s", name)
+	}
+
+	restCfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kubeconfig of AKS cluster %s", name)
+	}
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cluster{
+		name:     name,
+		settings: s,
+		client:   client,
+		cfg:      restCfg,
+		addons:   make(clusters.Addons),
+		l:        &sync.RWMutex{},
+	}, nil
+}
+
+func (c *Cluster) Name() string {
+	return c.name
+}
+
+func (c *Cluster) Type() clusters.Type {
+	return aksClusterType
+}
+
+func (c *Cluster) Version() (semver.Version, error) {
+	versionInfo, err := c.Client().ServerVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return semver.Parse(strings.TrimPrefix(versionInfo.String(), "v"))
+}
+
+// Cleanup deletes the AKS cluster, AKS deletes the node resource group of the cluster along with it
+func (c *Cluster) Cleanup(ctx context.Context) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	_, err := runAz(ctx, c.settings.args("aks", "delete", "--name", c.name, "--yes")...)
+	return errors.Wrapf(err, "failed to delete AKS cluster %s", c.name)
+}
+
+func (c *Cluster) Client() *kubernetes.Clientset {
+	return c.client
+}
+
+func (c *Cluster) Config() *rest.Config {
+	return c.cfg
+}
+
+func (c *Cluster) GetAddon(name clusters.AddonName) (clusters.Addon, error) {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	for addonName, addon := range c.addons {
+		if addonName == name {
+			return addon, nil
+		}
+	}
+
+	return nil, fmt.Errorf("addon %s not found", name)
+}
+
+func (c *Cluster) ListAddons() []clusters.Addon {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	addonList := make([]clusters.Addon, 0, len(c.addons))
+	for _, v := range c.addons {
+		addonList = append(addonList, v)
+	}
+
+	return addonList
+}
+
+func (c *Cluster) DeployAddon(ctx context.Context, addon clusters.Addon) error {
+	c.l.Lock()
+	if _, ok := c.addons[addon.Name()]; ok {
+		c.l.Unlock()
+		return fmt.Errorf("addon component %s is already loaded into cluster %s", addon.Name(), c.Name())
+	}
+	c.addons[addon.Name()] = addon
+	c.l.Unlock()
+
+	return addon.Deploy(ctx, c)
+}
+
+func (c *Cluster) DeleteAddon(ctx context.Context, addon clusters.Addon) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if _, ok := c.addons[addon.Name()]; !ok {
+		return nil
+	}
+
+	if err := addon.Delete(ctx, c); err != nil {
+		return err
+	}
+
+	delete(c.addons, addon.Name())
+
+	return nil
+}
+
+// DumpDiagnostics produces diagnostics data for the cluster at a given time.
+// It returns the path to directory containing all the diagnostic files and an error.
+func (c *Cluster) DumpDiagnostics(ctx context.Context, meta string) (string, error) {
+	outDir, err := os.MkdirTemp(os.TempDir(), clusters.DiagnosticOutDirectoryPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	err = clusters.DumpDiagnostics(ctx, c, meta, outDir)
+	return outDir, err
+}
+
+func (c *Cluster) IPFamily() clusters.IPFamily {
+	return clusters.IPv4
+}
@@ -0,0 +1,115 @@
+package aks
+
+import (
+	"context"
+	"errors"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/spf13/pflag"
+	"os"
+	"strings"
+)
+
+const (
+	envSubscriptionID = "AZURE_SUBSCRIPTION_ID"
+	envResourceGroup  = "AZURE_RESOURCE_GROUP"
+	envLocation       = "AZURE_LOCATION"
+)
+
+type aksProvider struct{}
+
+func (aksProvider) ClusterProvider(_ context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	s, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.location == "" {
+		return nil, errors.New(envLocation + " is not set")
+	}
+	if opts.IPFamily != "" && opts.IPFamily != clusters.IPv4 {
+		return nil, errors.New("AKS clusters only support the ipv4 IP family")
+	}
+
+	aksBuilder := newBuilder(s)
+	aksBuilder.Name = envName
+	if !opts.KubernetesVersion.EQ(semver.Version{}) {
+		aksBuilder.WithClusterVersion(opts.KubernetesVersion)
+	}
+	if opts.NodeType != "" {
+		aksBuilder.WithNodeVMSize(opts.NodeType)
+	}
+	if opts.NodeCount > 0 {
+		aksBuilder.WithNodeCount(opts.NodeCount)
+	}
+	if len(opts.Labels) > 0 {
+		aksBuilder.WithLabels(opts.Labels)
+	}
+
+	return aksBuilder, nil
+}
+
+func (aksProvider) NewFromExisting(ctx context.Context, envName string, opts cluster_providers.Options) (clusters.Cluster, error) {
+	s, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newFromExisting(ctx, s, envName)
+}
+
+// List finds the AKS clusters in the resource group carrying the smoke environment tag or named with the smoke environment prefix
+func (aksProvider) List(ctx context.Context, opts cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	s, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var managedClusters []managedCluster
+	if err := runAzJSON(ctx, &managedClusters, s.args("aks", "list")...); err != nil {
+		return nil, err
+	}
+
+	var envs []cluster_providers.Environment
+	for _, mc := range managedClusters {
+		if _, tagged := mc.Tags[smokeEnvironmentTag]; !tagged && !strings.HasPrefix(mc.Name, utils.EnvNamePrefix) {
+			continue
+		}
+		envs = append(envs, cluster_providers.Environment{
+			Name:              mc.Name,
+			CreatedAt:         mc.SystemData.CreatedAt,
+			KubernetesVersion: mc.CurrentKubernetesVersion,
+		})
+	}
+	return envs, nil
+}
+
+func (aksProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	vmSize := flags.String("aks-node-vm-size", defaultNodeVMSize, "The VM size of the nodes of AKS clusters")
+	nodeCount := flags.Int("aks-node-count", defaultNodeCount, "The number of nodes of AKS clusters")
+	return func(opts *cluster_providers.Options) {
+		opts.NodeType = *vmSize
+		opts.NodeCount = *nodeCount
+	}
+}
+
+// settingsFromEnv reads the resource group, the location and the optional subscription from the environment variables,
+// the location is overridden by the region of the options and is only required when creating clusters
+func settingsFromEnv(opts cluster_providers.Options) (settings, error) {
+	s := settings{
+		subscription:  os.Getenv(envSubscriptionID),
+		resourceGroup: os.Getenv(envResourceGroup),
+		location:      os.Getenv(envLocation),
+	}
+	if opts.Region != "" {
+		s.location = opts.Region
+	}
+	if s.resourceGroup == "" {
+		return s, errors.New(envResourceGroup + " is not set")
+	}
+	return s, nil
+}
+
+func init() {
+	cluster_providers.Register("aks", aksProvider{})
+}
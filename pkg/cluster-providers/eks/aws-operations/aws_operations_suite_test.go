@@ -0,0 +1,21 @@
+package aws_operations
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestAWSOperations(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EKS AWS Operations Suite")
+}
+
+var _ = BeforeEach(func() {
+	// the fake AWS changes the state of the resources right away, so there is no need to wait between polls
+	DeferCleanup(func(interval time.Duration) { pollInterval = interval }, pollInterval)
+	pollInterval = time.Millisecond
+
+	GinkgoT().Setenv(envKeyJournalDir, GinkgoT().TempDir())
+})
@@ -0,0 +1,23 @@
+package aws_operations
+
+import (
+	"errors"
+)
+
+// cleanupErrors collects the failures of a best-effort cleanup, which carries on when deleting a resource fails.
+// The failures of deleting resources that do not exist are dropped, so that cleaning up twice is safe.
+type cleanupErrors struct {
+	errs []error
+}
+
+func (e *cleanupErrors) add(err error) {
+	if err == nil || isNotFound(err) {
+		return
+	}
+	e.errs = append(e.errs, err)
+}
+
+// err returns all the collected failures as one error, or nil when there is none
+func (e *cleanupErrors) err() error {
+	return errors.Join(e.errs...)
+}
@@ -8,7 +8,7 @@ import (
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
-	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/weaveworks/eksctl/pkg/ami"
 	eksctlapi "github.com/weaveworks/eksctl/pkg/apis/eksctl.io/v1alpha5"
@@ -17,6 +17,8 @@ import (
 	"github.com/weaveworks/eksctl/pkg/nodebootstrap"
 	"k8s.io/client-go/kubernetes"
 	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -24,66 +26,138 @@ const (
 	DefaultNodeGroupName     = "default-node-group"
 	DefaultKubernetesSvcCIDR = "172.20.0.0/16"
 	kubernetesTagFormat      = "kubernetes.io/cluster/%s"
+	ownedTagValue            = "owned"
 	envKeyNodeSSHKeyName     = "EKS_NODE_SSH_KEY"
+	// SmokeEnvironmentTag is put on the EKS clusters created by kuma-smoke, its value is the name of the environment
+	SmokeEnvironmentTag = "kuma-smoke/environment"
+	// existingVPCTag is put on the EKS clusters created in an existing VPC, its value is the ID of the VPC,
+	// which is not deleted along with the cluster
+	existingVPCTag = "kuma-smoke/existing-vpc"
 )
 
-func CreateEKSClusterAll(ctx context.Context, cfg aws.Config, clusterName,
-	k8sMinorVersion, nodeMachineType string) error {
+// ClusterResources are the AWS resources created for an EKS cluster besides the cluster itself
+type ClusterResources struct {
+	VpcID            string
+	ClusterRoleArn   string
+	NodeRoleArn      string
+	LaunchTemplateID string
+}
 
-	ec2Client := ec2.NewFromConfig(cfg)
-	eksClient := eks.NewFromConfig(cfg)
-	iamClient := iam.NewFromConfig(cfg)
+// ClusterSpec describes an EKS cluster to create
+type ClusterSpec struct {
+	Name                   string
+	KubernetesMinorVersion string
+	NodeMachineType        string
+	NodeCount              int
+	// IPFamily is the IP family of the cluster networking, IPv4 when empty. The VPC of an IPv6 cluster is dual-stack,
+	// since EKS requires the subnets of IPv6 clusters to have IPv4 addresses as well.
+	IPFamily types.IpFamily
+	// VpcID and SubnetIDs are an existing VPC and its subnets to create the cluster in, a VPC is created for the
+	// cluster when they are empty. The subnets must be in at least two availability zones.
+	VpcID     string
+	SubnetIDs []string
+	// Tags are put on the cluster in addition to the smoke environment tag
+	Tags map[string]string
+}
 
-	clusterRoleArn, nodeRoleArn, err := createRoles(ctx, iamClient, clusterName)
+// CreateEKSClusterAll creates an EKS cluster with all the resources it depends on. The created resources are
+// recorded in a journal and deleted in the reverse order when it fails. The journal is left when the process is
+// interrupted, so that the resources can be deleted by cleaning up the cluster later.
+func CreateEKSClusterAll(ctx context.Context, cfg aws.Config, spec ClusterSpec) (*ClusterResources, error) {
+	return createEKSClusterAll(ctx, newAWSClients(cfg), spec)
+}
+
+func createEKSClusterAll(ctx context.Context, clients *awsClients, spec ClusterSpec) (*ClusterResources, error) {
+	journal, err := NewJournal(spec.Name)
 	if err != nil {
-		return errors.Wrap(err, "failed to create IAM roles")
+		return nil, err
 	}
-	subnetAvZones, err := getAvailabilityZones(ctx, ec2Client, cfg.Region)
+
+	resources, err := createClusterResources(ctx, clients, spec, journal)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get availability zones in region %s", cfg.Region)
+		// the context may already have expired, e.g. when creating the cluster timed out
+		rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), utils.CleanupTimeout)
+		defer cancel()
+		if rollbackErr := journal.rollback(rollbackCtx, clients); rollbackErr != nil {
+			return nil, fmt.Errorf("%w, and rolling back the created resources failed: %v", err, rollbackErr)
+		}
+		return nil, err
 	}
+	return resources, journal.Complete()
+}
+
+func createClusterResources(ctx context.Context, clients *awsClients, spec ClusterSpec, journal *Journal) (*ClusterResources, error) {
+	clusterName := spec.Name
+	ipv6 := spec.IPFamily == types.IpFamilyIpv6
 
-	vpcId, subnetIDs, err := createVPC(ctx, ec2Client, subnetAvZones)
+	ec2Client := clients.ec2
+	eksClient := clients.eks
+	resources := &ClusterResources{}
+
+	clusterRoleArn, nodeRoleArn, err := createRoles(ctx, clients.iam, journal, clusterName, ipv6)
 	if err != nil {
-		return errors.Wrap(err, "failed to create VPC")
+		return resources, errors.Wrap(err, "failed to create IAM roles")
+	}
+	resources.ClusterRoleArn = clusterRoleArn
+	resources.NodeRoleArn = nodeRoleArn
+
+	var vpcId string
+	var subnetIDs, subnetAvZones []string
+	if spec.VpcID != "" {
+		vpcId, subnetIDs = spec.VpcID, spec.SubnetIDs
+		subnetAvZones, err = validateExistingSubnets(ctx, ec2Client, vpcId, subnetIDs, ipv6)
+		if err != nil {
+			return resources, errors.Wrapf(err, "failed to validate the subnets of existing VPC %s", vpcId)
+		}
+	} else {
+		subnetAvZones, err = getAvailabilityZones(ctx, ec2Client, clients.region)
+		if err != nil {
+			return resources, errors.Wrapf(err, "failed to get availability zones in region %s", clients.region)
+		}
+
+		vpcId, subnetIDs, err = createVPC(ctx, ec2Client, journal, clusterName, subnetAvZones, ipv6)
+		if err != nil {
+			return resources, errors.Wrap(err, "failed to create VPC")
+		}
 	}
+	resources.VpcID = vpcId
 
-	cpSgId, err := createControlPlaneSecurityGroup(ctx, ec2Client, vpcId, clusterName)
+	cpSgId, err := createControlPlaneSecurityGroup(ctx, ec2Client, journal, vpcId, clusterName)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create control plane security group in VPC %s", vpcId)
+		return resources, errors.Wrapf(err, "failed to create control plane security group in VPC %s", vpcId)
 	}
 
-	_, err = createCluster(ctx, eksClient, clusterName, clusterRoleArn, k8sMinorVersion, cpSgId, subnetIDs)
+	_, err = createCluster(ctx, eksClient, journal, spec, clusterRoleArn, cpSgId, subnetIDs)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create EKS cluster %s", clusterName)
+		return resources, errors.Wrapf(err, "failed to create EKS cluster %s", clusterName)
 	}
 
 	activeCluster, err := waitForClusterActive(ctx, eksClient, clusterName)
 	if err != nil {
-		return errors.Wrapf(err, "failed while waiting for EKS cluster %s to become active", clusterName)
+		return resources, errors.Wrapf(err, "failed while waiting for EKS cluster %s to become active", clusterName)
 	}
 
-	sgId, err := createNodeSecurityGroup(ctx, ec2Client, vpcId, clusterName, activeCluster.ResourcesVpcConfig.SecurityGroupIds)
+	sgId, err := createNodeSecurityGroup(ctx, ec2Client, journal, vpcId, clusterName, activeCluster.ResourcesVpcConfig.SecurityGroupIds)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create security groups")
+		return resources, errors.Wrapf(err, "failed to create security groups")
 	}
 
-	_, kubeCfg, err := ClientForCluster(ctx, cfg, clusterName)
+	kubeClient, err := clients.kubeClient(ctx, clusterName)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get kube client for cluster %s", clusterName)
+		return resources, errors.Wrapf(err, "failed to get kube client for cluster %s", clusterName)
 	}
 
-	err = authorizeNodeGroup(kubeCfg, nodeRoleArn)
+	err = authorizeNodeGroup(kubeClient, nodeRoleArn)
 	if err != nil {
-		return errors.Wrapf(err, "failed to authorize node group to access cluster %s", clusterName)
+		return resources, errors.Wrapf(err, "failed to authorize node group to access cluster %s", clusterName)
 	}
 
-	amiId, err := resolveAMI(ctx, ec2Client, cfg.Region, k8sMinorVersion, nodeMachineType, eksctlapi.DefaultNodeImageFamily)
+	amiId, err := clients.resolveAMI(ctx, spec.KubernetesMinorVersion, spec.NodeMachineType, eksctlapi.DefaultNodeImageFamily)
 	if err != nil {
-		return errors.Wrap(err, "failed to resolve AMI")
+		return resources, errors.Wrap(err, "failed to resolve AMI")
 	}
 
-	clusterCfg := buildClusterConfig(clusterName, k8sMinorVersion, nodeMachineType, cfg.Region, amiId, subnetAvZones)
+	clusterCfg := buildClusterConfig(spec, clients.region, amiId, subnetAvZones)
 	ng := clusterCfg.NodeGroups[0]
 	clusterCfg.VPC.ID = vpcId
 	ng.Subnets = subnetIDs
@@ -92,60 +166,175 @@ func CreateEKSClusterAll(ctx context.Context, cfg aws.Config, clusterName,
 
 	err = clusterCfg.SetClusterState(activeCluster)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create cluster state object for cluster %s", clusterName)
+		return resources, errors.Wrapf(err, "failed to create cluster state object for cluster %s", clusterName)
+	}
+	if ipv6 {
+		ng.OverrideBootstrapCommand = aws.String(ipv6BootstrapCommand(activeCluster))
 	}
 
-	err = createNodeGroup(ctx, eksClient, ec2Client, clusterCfg)
+	resources.LaunchTemplateID, err = createNodeGroup(ctx, eksClient, ec2Client, journal, clusterCfg)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create EKS node group for cluster %s", clusterName)
+		return resources, errors.Wrapf(err, "failed to create EKS node group for cluster %s", clusterName)
 	}
 
-	return nil
+	return resources, nil
 }
 
+// DeleteEKSClusterAll deletes an EKS cluster with all the resources it depends on. It is best-effort: besides the
+// resources referred to by the cluster, the resources are found by the cluster tag and the role names, so that the
+// ones left by an earlier failed cleanup are deleted even if the cluster is already gone. It carries on when
+// deleting a resource fails and returns all the failures, and resources that do not exist are skipped,
+// so that it is safe to run it again. An existing VPC that the cluster was created in is not deleted.
 func DeleteEKSClusterAll(ctx context.Context, cfg aws.Config, clusterName string) error {
-	eksClient := eks.NewFromConfig(cfg)
-	ec2Client := ec2.NewFromConfig(cfg)
-	iamClient := iam.NewFromConfig(cfg)
+	return deleteEKSClusterAll(ctx, newAWSClients(cfg), clusterName)
+}
+
+func deleteEKSClusterAll(ctx context.Context, clients *awsClients, clusterName string) error {
+	eksClient := clients.eks
+	ec2Client := clients.ec2
+	var errs cleanupErrors
 
+	var vpcIDs, launchTemplateIDs []string
 	activeCluster, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{
 		Name: aws.String(clusterName),
 	})
-	if err != nil {
-		return errors.Wrap(err, "failed to read cluster information")
+	// the VPC of a cluster created in an existing VPC is left alone, only the security groups created in it are deleted
+	if err == nil && activeCluster.Cluster.ResourcesVpcConfig != nil && activeCluster.Cluster.Tags[existingVPCTag] == "" {
+		vpcIDs = append(vpcIDs, aws.ToString(activeCluster.Cluster.ResourcesVpcConfig.VpcId))
 	}
+	errs.add(errors.Wrap(err, "failed to read cluster information"))
 
-	vpcID := activeCluster.Cluster.ResourcesVpcConfig.VpcId
-	ngRole, launchTemplateId, err := deleteNodeGroup(ctx, eksClient, clusterName)
-	if err != nil {
-		return err
-	}
+	_, launchTemplateId, err := deleteNodeGroup(ctx, eksClient, clusterName)
+	errs.add(err)
 	if launchTemplateId != "" {
-		err = deleteNodeLaunchTemplate(ctx, ec2Client, launchTemplateId)
+		launchTemplateIDs = append(launchTemplateIDs, launchTemplateId)
+	}
+
+	errs.add(deleteCluster(ctx, eksClient, clusterName))
+
+	foundTemplateIDs, err := findNodeLaunchTemplates(ctx, ec2Client, clusterName)
+	errs.add(err)
+	for _, id := range unique(append(launchTemplateIDs, foundTemplateIDs...)) {
+		errs.add(deleteNodeLaunchTemplate(ctx, ec2Client, id))
+	}
+
+	foundVPCIDs, err := findClusterVPCs(ctx, ec2Client, clusterName)
+	errs.add(err)
+	for _, id := range unique(append(vpcIDs, foundVPCIDs...)) {
+		errs.add(deleteVPC(ctx, ec2Client, id))
+	}
+	errs.add(deleteClusterInternetGateways(ctx, ec2Client, clusterName))
+	errs.add(deleteClusterSecurityGroups(ctx, ec2Client, clusterName))
+
+	errs.add(deleteRoles(ctx, clients.iam, []string{clusterName + nodeRoleNameSuffix, clusterName + clusterRoleNameSuffix}))
+
+	return errors.Wrapf(errs.err(), "failed to clean up EKS cluster %s", clusterName)
+}
+
+func unique(ids []string) []string {
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// ValidateKubernetesVersion checks that EKS offers the minor version of Kubernetes in the region,
+// so that an unavailable version fails before any resource is created
+func ValidateKubernetesVersion(ctx context.Context, cfg aws.Config, k8sMinorVersion string) error {
+	eksClient := eks.NewFromConfig(cfg)
+
+	var available []string
+	paginator := eks.NewDescribeClusterVersionsPaginator(eksClient, &eks.DescribeClusterVersionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "failed to describe EKS cluster versions")
+		}
+		for _, version := range page.ClusterVersions {
+			if aws.ToString(version.ClusterVersion) == k8sMinorVersion {
+				return nil
+			}
+			available = append(available, aws.ToString(version.ClusterVersion))
 		}
 	}
+	return errors.Errorf("Kubernetes %s is not available on EKS in region %s, available versions are: %s",
+		k8sMinorVersion, cfg.Region, strings.Join(available, ", "))
+}
 
-	err = deleteRoles(ctx, iamClient, []string{ngRole, *activeCluster.Cluster.RoleArn})
-	if err != nil {
-		return err
-	}
+// ClusterIPFamily returns the IP family of the networking of an EKS cluster
+func ClusterIPFamily(ctx context.Context, cfg aws.Config, clusterName string) (types.IpFamily, error) {
+	eksClient := eks.NewFromConfig(cfg)
 
-	err = deleteCluster(ctx, eksClient, clusterName)
+	resp, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(clusterName)})
 	if err != nil {
-		return err
+		return "", errors.Wrapf(err, "failed to describe EKS cluster %s", clusterName)
+	}
+	networkConfig := resp.Cluster.KubernetesNetworkConfig
+	if networkConfig == nil || networkConfig.IpFamily == "" {
+		return types.IpFamilyIpv4, nil
 	}
+	return networkConfig.IpFamily, nil
+}
 
-	return deleteVPC(ctx, ec2Client, *vpcID)
+// SmokeCluster is an EKS cluster created by kuma-smoke
+type SmokeCluster struct {
+	Name              string
+	CreatedAt         time.Time
+	KubernetesVersion string
 }
 
-func createCluster(ctx context.Context, eksClient *eks.Client,
-	clusterName, clusterRoleArn, version, cpSgId string, subnetIDs []string) (*types.Cluster, error) {
+// ListSmokeClusters finds the EKS clusters carrying the smoke environment tag, or named with the smoke environment
+// prefix for the clusters created before the tag was introduced
+func ListSmokeClusters(ctx context.Context, cfg aws.Config, namePrefix string) ([]SmokeCluster, error) {
+	eksClient := eks.NewFromConfig(cfg)
+
+	var smokeClusters []SmokeCluster
+	paginator := eks.NewListClustersPaginator(eksClient, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list EKS clusters")
+		}
+
+		for _, name := range page.Clusters {
+			resp, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+			if err != nil {
+				var notFound *types.ResourceNotFoundException
+				if errors.As(err, &notFound) {
+					// deleted after being listed
+					continue
+				}
+				return nil, errors.Wrapf(err, "failed to describe EKS cluster %s", name)
+			}
+
+			cluster := resp.Cluster
+			if _, tagged := cluster.Tags[SmokeEnvironmentTag]; !tagged && !strings.HasPrefix(name, namePrefix) {
+				continue
+			}
+			smokeClusters = append(smokeClusters, SmokeCluster{
+				Name:              name,
+				CreatedAt:         aws.ToTime(cluster.CreatedAt),
+				KubernetesVersion: aws.ToString(cluster.Version),
+			})
+		}
+	}
+	return smokeClusters, nil
+}
+
+func createCluster(ctx context.Context, eksClient eksAPI, journal *Journal,
+	spec ClusterSpec, clusterRoleArn, cpSgId string, subnetIDs []string) (*types.Cluster, error) {
+	clusterName := spec.Name
+	clusterTags := map[string]string{SmokeEnvironmentTag: clusterName}
+	for k, v := range spec.Tags {
+		clusterTags[k] = v
+	}
+	if spec.VpcID != "" {
+		clusterTags[existingVPCTag] = spec.VpcID
+	}
+
 	eksCreateInput := &eks.CreateClusterInput{
 		Name:    &clusterName,
 		RoleArn: &clusterRoleArn,
-		Version: aws.String(version),
+		Version: aws.String(spec.KubernetesMinorVersion),
+		Tags:    clusterTags,
 
 		AccessConfig: &types.CreateAccessConfigRequest{
 			AuthenticationMode:                      types.AuthenticationModeConfigMap,
@@ -161,20 +350,24 @@ func createCluster(ctx context.Context, eksClient *eks.Client,
 			ServiceIpv4Cidr: aws.String(DefaultKubernetesSvcCIDR),
 		},
 	}
+	if spec.IPFamily == types.IpFamilyIpv6 {
+		// the service CIDR of IPv6 clusters is assigned by EKS
+		eksCreateInput.KubernetesNetworkConfig = &types.KubernetesNetworkConfigRequest{IpFamily: spec.IPFamily}
+	}
 
 	clusterOutput, err := eksClient.CreateCluster(ctx, eksCreateInput)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create EKS cluster %s", clusterName)
 	}
-	return clusterOutput.Cluster, nil
+	return clusterOutput.Cluster, journal.record(ResourceCluster, clusterName, "")
 }
 
-func buildClusterConfig(clusterName, minorVersion, nodeMachineType, region, amiId string, subnetAvZones []string) *eksctlapi.ClusterConfig {
+func buildClusterConfig(spec ClusterSpec, region, amiId string, subnetAvZones []string) *eksctlapi.ClusterConfig {
 	clusterCfg := eksctlapi.NewClusterConfig()
 
-	clusterCfg.Metadata.Name = clusterName
+	clusterCfg.Metadata.Name = spec.Name
 	clusterCfg.Metadata.Region = region
-	clusterCfg.Metadata.Version = minorVersion
+	clusterCfg.Metadata.Version = spec.KubernetesMinorVersion
 	clusterCfg.KubernetesNetworkConfig.ServiceIPv4CIDR = DefaultKubernetesSvcCIDR
 	clusterCfg.Status = &eksctlapi.ClusterStatus{}
 
@@ -183,12 +376,12 @@ func buildClusterConfig(clusterName, minorVersion, nodeMachineType, region, amiI
 	ng.ContainerRuntime = aws.String(eksctlapi.ContainerRuntimeContainerD)
 	ng.AMIFamily = eksctlapi.DefaultNodeImageFamily
 	ng.AMI = amiId
-	ng.InstanceType = nodeMachineType
+	ng.InstanceType = spec.NodeMachineType
 	ng.AvailabilityZones = subnetAvZones
 	ng.ScalingConfig = &eksctlapi.ScalingConfig{
-		DesiredCapacity: aws.Int(1),
-		MinSize:         aws.Int(1),
-		MaxSize:         aws.Int(1),
+		DesiredCapacity: aws.Int(spec.NodeCount),
+		MinSize:         aws.Int(spec.NodeCount),
+		MaxSize:         aws.Int(spec.NodeCount),
 	}
 
 	nodeKeyName := os.Getenv(envKeyNodeSSHKeyName)
@@ -200,10 +393,18 @@ func buildClusterConfig(clusterName, minorVersion, nodeMachineType, region, amiI
 	return clusterCfg
 }
 
-func waitForClusterActive(ctx context.Context, eksClient *eks.Client, clusterName string) (*types.Cluster, error) {
+// ipv6BootstrapCommand bootstraps the nodes of an IPv6 cluster. The bootstrap script of eksctl for AL2 does not pass
+// the IP family to the bootstrap script of the AMI, which would configure kubelet for IPv4 otherwise.
+func ipv6BootstrapCommand(cluster *types.Cluster) string {
+	return fmt.Sprintf("/etc/eks/bootstrap.sh %s --apiserver-endpoint %s --b64-cluster-ca %s --ip-family ipv6 --service-ipv6-cidr %s",
+		aws.ToString(cluster.Name), aws.ToString(cluster.Endpoint), aws.ToString(cluster.CertificateAuthority.Data),
+		aws.ToString(cluster.KubernetesNetworkConfig.ServiceIpv6Cidr))
+}
+
+func waitForClusterActive(ctx context.Context, eksClient eksAPI, clusterName string) (*types.Cluster, error) {
 	childCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 	for {
 		select {
@@ -248,11 +449,15 @@ func authorizeNodeGroup(clientSet kubernetes.Interface, nodeRoleArn string) erro
 	return nil
 }
 
-func createNodeGroup(ctx context.Context, eksClient *eks.Client, ec2Client *ec2.Client, clusterCfg *eksctlapi.ClusterConfig) error {
+// createNodeGroup creates the node group of the cluster and returns the ID of the launch template used by its nodes
+func createNodeGroup(ctx context.Context, eksClient eksAPI, ec2Client ec2API, journal *Journal, clusterCfg *eksctlapi.ClusterConfig) (string, error) {
 	nodeGroup := clusterCfg.NodeGroups[0]
 	launchTemplateId, err := createNodeLaunchTemplate(ctx, ec2Client, clusterCfg)
 	if err != nil {
-		return errors.Wrap(err, "failed to create launch template")
+		return "", errors.Wrap(err, "failed to create launch template")
+	}
+	if err := journal.record(ResourceLaunchTemplate, launchTemplateId, ""); err != nil {
+		return launchTemplateId, err
 	}
 
 	input := &eks.CreateNodegroupInput{
@@ -272,16 +477,19 @@ func createNodeGroup(ctx context.Context, eksClient *eks.Client, ec2Client *ec2.
 
 	_, err = eksClient.CreateNodegroup(ctx, input)
 	if err != nil {
-		return err
+		return launchTemplateId, err
+	}
+	if err := journal.record(ResourceNodeGroup, nodeGroup.Name, clusterCfg.Metadata.Name); err != nil {
+		return launchTemplateId, err
 	}
 
-	return waitForNodeGroupReady(ctx, eksClient, clusterCfg.Metadata.Name, nodeGroup.Name)
+	return launchTemplateId, waitForNodeGroupReady(ctx, eksClient, clusterCfg.Metadata.Name, nodeGroup.Name)
 }
 
-func waitForNodeGroupReady(ctx context.Context, eksClient *eks.Client, clusterName, nodeGroupName string) error {
+func waitForNodeGroupReady(ctx context.Context, eksClient eksAPI, clusterName, nodeGroupName string) error {
 	childCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -306,7 +514,7 @@ func waitForNodeGroupReady(ctx context.Context, eksClient *eks.Client, clusterNa
 	}
 }
 
-func createNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, clusterCfg *eksctlapi.ClusterConfig) (string, error) {
+func createNodeLaunchTemplate(ctx context.Context, ec2Client ec2API, clusterCfg *eksctlapi.ClusterConfig) (string, error) {
 	nodeGroup := clusterCfg.NodeGroups[0]
 	bootstrap := nodebootstrap.NewAL2Bootstrapper(clusterCfg, nodeGroup, nodeGroup.ClusterDNS)
 	userdata, err := bootstrap.UserData()
@@ -315,7 +523,8 @@ func createNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, cluste
 	}
 
 	input := &ec2.CreateLaunchTemplateInput{
-		LaunchTemplateName: aws.String(fmt.Sprintf("%s-node-template", clusterCfg.Metadata.Name)),
+		LaunchTemplateName: aws.String(nodeLaunchTemplateName(clusterCfg.Metadata.Name)),
+		TagSpecifications:  clusterTagSpecifications(ec2Types.ResourceTypeLaunchTemplate, clusterCfg.Metadata.Name),
 		LaunchTemplateData: &ec2Types.RequestLaunchTemplateData{
 			ImageId:          aws.String(nodeGroup.AMI),
 			InstanceType:     ec2Types.InstanceType(nodeGroup.InstanceType),
@@ -336,7 +545,7 @@ func createNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, cluste
 					Tags: []ec2Types.Tag{
 						{
 							Key:   aws.String(fmt.Sprintf(kubernetesTagFormat, clusterCfg.Metadata.Name)),
-							Value: aws.String("owned"),
+							Value: aws.String(ownedTagValue),
 						},
 					},
 				},
@@ -356,6 +565,33 @@ func createNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, cluste
 	return *output.LaunchTemplate.LaunchTemplateId, nil
 }
 
+func nodeLaunchTemplateName(clusterName string) string {
+	return fmt.Sprintf("%s-node-template", clusterName)
+}
+
+// findNodeLaunchTemplates finds the launch templates of the nodes of a cluster by the cluster tag,
+// or by the name for the ones created before they were tagged
+func findNodeLaunchTemplates(ctx context.Context, ec2Client ec2API, clusterName string) ([]string, error) {
+	filters := []ec2Types.Filter{
+		clusterTagFilter(clusterName),
+		{Name: aws.String("launch-template-name"), Values: []string{nodeLaunchTemplateName(clusterName)}},
+	}
+
+	var ids []string
+	for _, filter := range filters {
+		output, err := ec2Client.DescribeLaunchTemplates(ctx, &ec2.DescribeLaunchTemplatesInput{
+			Filters: []ec2Types.Filter{filter},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find the launch templates of cluster %s", clusterName)
+		}
+		for _, template := range output.LaunchTemplates {
+			ids = append(ids, aws.ToString(template.LaunchTemplateId))
+		}
+	}
+	return ids, nil
+}
+
 func resolveAMI(ctx context.Context, ec2Client *ec2.Client, region, k8sMinorVersion, instanceType, amiFamily string) (string, error) {
 	resolver := ami.NewAutoResolver(ec2Client)
 
@@ -366,7 +602,7 @@ func resolveAMI(ctx context.Context, ec2Client *ec2.Client, region, k8sMinorVers
 	return id, nil
 }
 
-func deleteNodeGroup(ctx context.Context, eksClient *eks.Client, clusterName string) (string, string, error) {
+func deleteNodeGroup(ctx context.Context, eksClient eksAPI, clusterName string) (string, string, error) {
 	var notFoundErr *types.ResourceNotFoundException
 	describeNGInput := &eks.DescribeNodegroupInput{
 		ClusterName:   aws.String(clusterName),
@@ -391,7 +627,7 @@ func deleteNodeGroup(ctx context.Context, eksClient *eks.Client, clusterName str
 		return "", "", err
 	}
 
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -407,7 +643,11 @@ func deleteNodeGroup(ctx context.Context, eksClient *eks.Client, clusterName str
 			if err != nil {
 				if errors.As(err, &notFoundErr) {
 					// the node group has already been deleted successfully
-					return aws.ToString(ngInfo.Nodegroup.NodeRole), aws.ToString(ngInfo.Nodegroup.LaunchTemplate.Id), nil
+					var launchTemplateId string
+					if ngInfo.Nodegroup.LaunchTemplate != nil {
+						launchTemplateId = aws.ToString(ngInfo.Nodegroup.LaunchTemplate.Id)
+					}
+					return aws.ToString(ngInfo.Nodegroup.NodeRole), launchTemplateId, nil
 				} else {
 					return "", "", errors.Wrap(err, fmt.Sprintf("failed to describe node group %s of cluster %s", DefaultNodeGroupName, clusterName))
 				}
@@ -416,7 +656,7 @@ func deleteNodeGroup(ctx context.Context, eksClient *eks.Client, clusterName str
 	}
 }
 
-func deleteNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, launchTemplateId string) error {
+func deleteNodeLaunchTemplate(ctx context.Context, ec2Client ec2API, launchTemplateId string) error {
 	deleteLaunchTmplInput := &ec2.DeleteLaunchTemplateInput{
 		LaunchTemplateId: aws.String(launchTemplateId),
 	}
@@ -427,7 +667,7 @@ func deleteNodeLaunchTemplate(ctx context.Context, ec2Client *ec2.Client, launch
 	return nil
 }
 
-func deleteCluster(ctx context.Context, eksClient *eks.Client, clusterName string) error {
+func deleteCluster(ctx context.Context, eksClient eksAPI, clusterName string) error {
 	var notFoundErr *types.ResourceNotFoundException
 	clusterInput := &eks.DeleteClusterInput{
 		Name: aws.String(clusterName),
@@ -437,7 +677,7 @@ func deleteCluster(ctx context.Context, eksClient *eks.Client, clusterName strin
 		return err
 	}
 
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -0,0 +1,379 @@
+package aws_operations
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"time"
+)
+
+func newFakeClients(fake *fakeAWS, kubeClient kubernetes.Interface) *awsClients {
+	return &awsClients{
+		ec2:    fake,
+		eks:    fake,
+		iam:    fake,
+		elb:    fakeELB{fake},
+		elbv2:  fakeELBv2{fake},
+		region: "us-east-1",
+		kubeClient: func(context.Context, string) (kubernetes.Interface, error) {
+			return kubeClient, nil
+		},
+		resolveAMI: func(context.Context, string, string, string) (string, error) {
+			return "ami-0123456789abcdef0", nil
+		},
+	}
+}
+
+var _ = Describe("EKS cluster operations", func() {
+	var fake *fakeAWS
+	var kubeClient *k8sfake.Clientset
+	var clients *awsClients
+	var spec ClusterSpec
+
+	BeforeEach(func() {
+		fake = newFakeAWS()
+		kubeClient = k8sfake.NewSimpleClientset()
+		clients = newFakeClients(fake, kubeClient)
+		spec = ClusterSpec{
+			Name:                   "smoke-eks",
+			KubernetesMinorVersion: "1.31",
+			NodeMachineType:        "t3.large",
+			NodeCount:              2,
+		}
+	})
+
+	Describe("creating a cluster", func() {
+		It("should create the cluster with everything it depends on", func(ctx SpecContext) {
+			resources, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fake.count("eks-cluster")).To(Equal(1))
+			Expect(fake.count("node-group")).To(Equal(1))
+			Expect(fake.count("launch-template")).To(Equal(1))
+			Expect(fake.count("iam-role")).To(Equal(2))
+			Expect(fake.count("vpc")).To(Equal(1))
+			Expect(fake.count("subnet")).To(Equal(2))
+			Expect(fake.count("internet-gateway")).To(Equal(1))
+			Expect(fake.count("route-table")).To(Equal(1))
+			Expect(fake.count("security-group")).To(Equal(2))
+
+			Expect(fake.vpcs).To(HaveKey(resources.VpcID))
+			Expect(fake.launchTemplates).To(HaveKey(resources.LaunchTemplateID))
+			Expect(fake.roles[spec.Name+clusterRoleNameSuffix].arn).To(Equal(resources.ClusterRoleArn))
+			Expect(fake.roles[spec.Name+nodeRoleNameSuffix].arn).To(Equal(resources.NodeRoleArn))
+			Expect(fake.roles[spec.Name+nodeRoleNameSuffix].inlinePolicies).To(BeEmpty())
+
+			authConfigMap, err := kubeClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, "aws-auth", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authConfigMap.Data["mapRoles"]).To(ContainSubstring(resources.NodeRoleArn))
+
+			Expect(LoadJournal(spec.Name)).To(BeNil())
+		})
+
+		It("should create dual-stack networking for IPv6 clusters", func(ctx SpecContext) {
+			spec.IPFamily = types.IpFamilyIpv6
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			var subnetIPv6CIDRs []string
+			for _, subnet := range fake.subnets {
+				Expect(subnet.Ipv6CidrBlockAssociationSet).To(HaveLen(1))
+				Expect(aws.ToBool(subnet.AssignIpv6AddressOnCreation)).To(BeTrue())
+				subnetIPv6CIDRs = append(subnetIPv6CIDRs, aws.ToString(subnet.Ipv6CidrBlockAssociationSet[0].Ipv6CidrBlock))
+			}
+			Expect(subnetIPv6CIDRs).To(ConsistOf("2600:1f18:1234:5601::/64", "2600:1f18:1234:5602::/64"))
+			Expect(fake.roles[spec.Name+nodeRoleNameSuffix].inlinePolicies).To(HaveKey("CNIIPv6Policy"))
+			Expect(fake.clusters[spec.Name].KubernetesNetworkConfig.IpFamily).To(Equal(types.IpFamilyIpv6))
+		})
+
+		It("should refuse to create a cluster when the journal of an earlier creation is left", func(ctx SpecContext) {
+			_, err := NewJournal(spec.Name)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("clean up the cluster first")))
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+
+		DescribeTable("should roll back the created resources when a step fails",
+			func(ctx SpecContext, operation string) {
+				fake.failOn(operation)
+
+				_, err := createEKSClusterAll(ctx, clients, spec)
+				Expect(err).To(MatchError(ContainSubstring("injected failure of " + operation)))
+				Expect(err.Error()).ToNot(ContainSubstring("rolling back"))
+
+				Expect(fake.remaining()).To(BeEmpty())
+				Expect(LoadJournal(spec.Name)).To(BeNil())
+			},
+			Entry(nil, "CreateRole"),
+			Entry(nil, "PutRolePolicy"),
+			Entry(nil, "AttachRolePolicy"),
+			Entry(nil, "DescribeAvailabilityZones"),
+			Entry(nil, "CreateVpc"),
+			Entry(nil, "ModifyVpcAttribute"),
+			Entry(nil, "CreateInternetGateway"),
+			Entry(nil, "AttachInternetGateway"),
+			Entry(nil, "CreateRouteTable"),
+			Entry(nil, "CreateRoute"),
+			Entry(nil, "CreateSubnet"),
+			Entry(nil, "ModifySubnetAttribute"),
+			Entry(nil, "AssociateRouteTable"),
+			Entry(nil, "CreateSecurityGroup"),
+			Entry(nil, "CreateCluster"),
+			Entry(nil, "AuthorizeSecurityGroupIngress"),
+			Entry(nil, "CreateLaunchTemplate"),
+			Entry(nil, "CreateNodegroup"),
+		)
+
+		It("should roll back the created resources when the cluster is unreachable", func(ctx SpecContext) {
+			clients.kubeClient = func(context.Context, string) (kubernetes.Interface, error) {
+				return nil, context.DeadlineExceeded
+			}
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("failed to get kube client")))
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+
+		It("should keep the resources that failed to be rolled back in the journal", func(ctx SpecContext) {
+			fake.failOn("CreateNodegroup")
+			fake.failOn("DeleteVpc")
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("rolling back the created resources failed")))
+			Expect(err).To(MatchError(ContainSubstring("injected failure of DeleteVpc")))
+			Expect(fake.remaining()).To(ConsistOf(HavePrefix("vpc ")))
+
+			journal, err := LoadJournal(spec.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(journal.Entries).To(HaveExactElements(HaveField("Kind", ResourceVPC)))
+
+			var apiErr smithy.APIError
+			Expect(errors.As(journal.rollback(ctx, clients), &apiErr)).To(BeTrue())
+			Expect(apiErr.ErrorCode()).To(Equal("InjectedFailure"))
+
+			fake.recover()
+			Expect(journal.rollback(ctx, clients)).To(Succeed())
+			Expect(fake.remaining()).To(BeEmpty())
+			Expect(LoadJournal(spec.Name)).To(BeNil())
+		})
+
+		It("should roll back an interrupted creation from the journal it left", func(ctx SpecContext) {
+			journal, err := NewJournal(spec.Name)
+			Expect(err).ToNot(HaveOccurred())
+			// the creation is interrupted before it fails, so it never rolls back
+			fake.failOn("CreateNodegroup")
+			_, err = createClusterResources(ctx, clients, spec, journal)
+			Expect(err).To(HaveOccurred())
+			Expect(fake.remaining()).ToNot(BeEmpty())
+
+			fake.recover()
+			left, err := LoadJournal(spec.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(left.Entries).To(Equal(journal.Entries))
+			Expect(left.rollback(ctx, clients)).To(Succeed())
+
+			Expect(fake.remaining()).To(BeEmpty())
+			Expect(LoadJournal(spec.Name)).To(BeNil())
+		})
+	})
+
+	Describe("deleting a cluster", func() {
+		BeforeEach(func(ctx SpecContext) {
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should delete the cluster with everything it depends on", func(ctx SpecContext) {
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+
+		It("should be safe to run again", func(ctx SpecContext) {
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+
+		It("should find the resources left when the cluster is already gone", func(ctx SpecContext) {
+			_, err := fake.DeleteNodegroup(ctx, &eks.DeleteNodegroupInput{
+				ClusterName:   aws.String(spec.Name),
+				NodegroupName: aws.String(DefaultNodeGroupName),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = fake.DeleteCluster(ctx, &eks.DeleteClusterInput{Name: aws.String(spec.Name)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+
+		It("should carry on when deleting a resource fails and report all the failures", func(ctx SpecContext) {
+			fake.failOn("DeleteLaunchTemplate")
+			fake.failOn("DetachRolePolicy")
+
+			err := deleteEKSClusterAll(ctx, clients, spec.Name)
+			Expect(err).To(MatchError(ContainSubstring("injected failure of DeleteLaunchTemplate")))
+			Expect(err).To(MatchError(ContainSubstring("injected failure of DetachRolePolicy")))
+			Expect(fake.remaining()).To(ConsistOf(HavePrefix("launch-template "), HavePrefix("iam-role "), HavePrefix("iam-role ")))
+			var apiErr smithy.APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.ErrorCode()).To(Equal("InjectedFailure"))
+
+			fake.recover()
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(BeEmpty())
+		})
+	})
+
+	Describe("waiting for the load balancers to be deleted", func() {
+		var vpcID string
+
+		BeforeEach(func(ctx SpecContext) {
+			resources, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+			vpcID = resources.VpcID
+		})
+
+		It("should wait until the load balancers of the cluster are deleted", func(ctx SpecContext) {
+			fake.addLoadBalancer("a1b2c3", true, vpcID, spec.Name, 2)
+			fake.addLoadBalancer("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/k8s-nlb/1", false, vpcID, spec.Name, 3)
+
+			Expect(waitForLoadBalancersDeleted(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.count("load-balancer")).To(BeZero())
+		})
+
+		It("should ignore the load balancers of other clusters", func(ctx SpecContext) {
+			fake.addLoadBalancer("same-vpc", true, vpcID, "other-cluster", -1)
+			fake.addLoadBalancer("other-vpc", false, "vpc-other", "other-cluster", -1)
+
+			Expect(waitForLoadBalancersDeleted(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.count("load-balancer")).To(Equal(2))
+			Expect(fake.describedTags).To(ConsistOf("same-vpc"))
+		})
+
+		It("should time out when a load balancer is never deleted", func(ctx SpecContext) {
+			DeferCleanup(func(timeout time.Duration) { loadBalancerDeletionTimeout = timeout }, loadBalancerDeletionTimeout)
+			loadBalancerDeletionTimeout = 50 * time.Millisecond
+			fake.addLoadBalancer("stuck", true, vpcID, spec.Name, -1)
+			fake.addLoadBalancer("deleted", false, vpcID, spec.Name, 1)
+
+			err := waitForLoadBalancersDeleted(ctx, clients, spec.Name)
+			Expect(err).To(MatchError(ContainSubstring("still existing: stuck")))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(fake.remaining()).To(ContainElement("load-balancer stuck"))
+			Expect(fake.remaining()).ToNot(ContainElement("load-balancer deleted"))
+		})
+
+		It("should fail when the load balancers can't be listed", func(ctx SpecContext) {
+			fake.failOn("DescribeLoadBalancers")
+
+			err := waitForLoadBalancersDeleted(ctx, clients, spec.Name)
+			Expect(err).To(MatchError(ContainSubstring("injected failure of DescribeLoadBalancers")))
+		})
+	})
+
+	Describe("using an existing VPC", func() {
+		var existingNetwork []string
+
+		createSubnet := func(ctx context.Context, vpcID, availabilityZone string) string {
+			output, err := fake.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+				VpcId:            aws.String(vpcID),
+				CidrBlock:        aws.String("10.0.0.0/24"),
+				AvailabilityZone: aws.String(availabilityZone),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return aws.ToString(output.Subnet.SubnetId)
+		}
+
+		BeforeEach(func(ctx SpecContext) {
+			vpcOutput, err := fake.CreateVpc(ctx, &ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")})
+			Expect(err).ToNot(HaveOccurred())
+			spec.VpcID = aws.ToString(vpcOutput.Vpc.VpcId)
+			spec.SubnetIDs = []string{createSubnet(ctx, spec.VpcID, "us-east-1a"), createSubnet(ctx, spec.VpcID, "us-east-1c")}
+			existingNetwork = fake.remaining()
+		})
+
+		It("should create the cluster in the VPC without creating any networking", func(ctx SpecContext) {
+			resources, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resources.VpcID).To(Equal(spec.VpcID))
+			Expect(fake.count("vpc")).To(Equal(1))
+			Expect(fake.count("subnet")).To(Equal(2))
+			Expect(fake.count("internet-gateway")).To(BeZero())
+			Expect(fake.count("route-table")).To(BeZero())
+			Expect(fake.count("security-group")).To(Equal(2))
+			Expect(fake.clusters[spec.Name].ResourcesVpcConfig.SubnetIds).To(Equal(spec.SubnetIDs))
+			Expect(fake.nodeGroups[spec.Name+"/"+DefaultNodeGroupName].Subnets).To(Equal(spec.SubnetIDs))
+		})
+
+		It("should leave the VPC alone when deleting the cluster", func(ctx SpecContext) {
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(Equal(existingNetwork))
+		})
+
+		It("should leave the VPC alone when cleaning up a cluster that is already gone", func(ctx SpecContext) {
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = fake.DeleteNodegroup(ctx, &eks.DeleteNodegroupInput{
+				ClusterName:   aws.String(spec.Name),
+				NodegroupName: aws.String(DefaultNodeGroupName),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = fake.DeleteCluster(ctx, &eks.DeleteClusterInput{Name: aws.String(spec.Name)})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deleteEKSClusterAll(ctx, clients, spec.Name)).To(Succeed())
+			Expect(fake.remaining()).To(Equal(existingNetwork))
+		})
+
+		It("should leave the VPC alone when rolling back", func(ctx SpecContext) {
+			fake.failOn("CreateNodegroup")
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("injected failure of CreateNodegroup")))
+			Expect(fake.remaining()).To(Equal(existingNetwork))
+		})
+
+		It("should refuse subnets of another VPC", func(ctx SpecContext) {
+			vpcOutput, err := fake.CreateVpc(ctx, &ec2.CreateVpcInput{CidrBlock: aws.String("10.1.0.0/16")})
+			Expect(err).ToNot(HaveOccurred())
+			spec.SubnetIDs[1] = createSubnet(ctx, aws.ToString(vpcOutput.Vpc.VpcId), "us-east-1c")
+
+			_, err = createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("is in VPC " + aws.ToString(vpcOutput.Vpc.VpcId))))
+			Expect(fake.count("eks-cluster")).To(BeZero())
+		})
+
+		It("should refuse subnets in a single availability zone", func(ctx SpecContext) {
+			spec.SubnetIDs[1] = createSubnet(ctx, spec.VpcID, "us-east-1a")
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("the subnets are in 1 availability zones")))
+			Expect(fake.count("eks-cluster")).To(BeZero())
+		})
+
+		It("should refuse IPv4 only subnets for IPv6 clusters", func(ctx SpecContext) {
+			spec.IPFamily = types.IpFamilyIpv6
+
+			_, err := createEKSClusterAll(ctx, clients, spec)
+			Expect(err).To(MatchError(ContainSubstring("has no IPv6 CIDR block")))
+			Expect(fake.count("eks-cluster")).To(BeZero())
+		})
+	})
+})
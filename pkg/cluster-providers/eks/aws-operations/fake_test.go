@@ -0,0 +1,1284 @@
+package aws_operations
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2Types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fakeAWS is an in-memory fake of the EC2, EKS, IAM and ELB APIs. It keeps the resources that the clusters are made of,
+// enforces the dependencies between them the way AWS does, e.g. a VPC can't be deleted while it has subnets,
+// and fails the operations that a failure is injected into.
+type fakeAWS struct {
+	mu       sync.Mutex
+	nextID   int
+	failures map[string]error
+
+	vpcs             map[string]*ec2Types.Vpc
+	internetGateways map[string]*ec2Types.InternetGateway
+	routeTables      map[string]*ec2Types.RouteTable
+	subnets          map[string]*ec2Types.Subnet
+	securityGroups   map[string]*ec2Types.SecurityGroup
+	launchTemplates  map[string]*ec2Types.LaunchTemplate
+	roles            map[string]*fakeRole
+	clusters         map[string]*types.Cluster
+	nodeGroups       map[string]*types.Nodegroup
+	loadBalancers    map[string]*fakeLoadBalancer
+	// describedTags lists the load balancers that the tags were described of
+	describedTags []string
+}
+
+// fakeLoadBalancer is a load balancer created by the cloud provider of a cluster, it is deleted after being
+// listed a number of times, the way the cloud provider deletes it some time after its service is gone
+type fakeLoadBalancer struct {
+	classic   bool
+	vpcID     string
+	tags      map[string]string
+	pollsLeft int
+}
+
+type fakeRole struct {
+	arn             string
+	managedPolicies []string
+	inlinePolicies  map[string]string
+}
+
+var (
+	_ ec2API   = &fakeAWS{}
+	_ eksAPI   = &fakeAWS{}
+	_ iamAPI   = &fakeAWS{}
+	_ elbAPI   = fakeELB{}
+	_ elbv2API = fakeELBv2{}
+)
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{
+		failures:         map[string]error{},
+		vpcs:             map[string]*ec2Types.Vpc{},
+		internetGateways: map[string]*ec2Types.InternetGateway{},
+		routeTables:      map[string]*ec2Types.RouteTable{},
+		subnets:          map[string]*ec2Types.Subnet{},
+		securityGroups:   map[string]*ec2Types.SecurityGroup{},
+		launchTemplates:  map[string]*ec2Types.LaunchTemplate{},
+		roles:            map[string]*fakeRole{},
+		clusters:         map[string]*types.Cluster{},
+		nodeGroups:       map[string]*types.Nodegroup{},
+		loadBalancers:    map[string]*fakeLoadBalancer{},
+	}
+}
+
+// failOn makes every call of an operation fail, e.g. failOn("CreateSubnet")
+func (f *fakeAWS) failOn(operation string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = &smithy.GenericAPIError{Code: "InjectedFailure", Message: "injected failure of " + operation}
+}
+
+// recover stops failing all the operations
+func (f *fakeAWS) recover() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = map[string]error{}
+}
+
+// remaining lists the resources left, the main route table and the default security group of a VPC
+// are left out since they go away with the VPC
+func (f *fakeAWS) remaining() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var resources []string
+	for id := range f.vpcs {
+		resources = append(resources, "vpc "+id)
+	}
+	for id := range f.internetGateways {
+		resources = append(resources, "internet-gateway "+id)
+	}
+	for id, rt := range f.routeTables {
+		if !isMainRouteTable(rt) {
+			resources = append(resources, "route-table "+id)
+		}
+	}
+	for id := range f.subnets {
+		resources = append(resources, "subnet "+id)
+	}
+	for id, sg := range f.securityGroups {
+		if aws.ToString(sg.GroupName) != "default" {
+			resources = append(resources, "security-group "+id)
+		}
+	}
+	for id := range f.launchTemplates {
+		resources = append(resources, "launch-template "+id)
+	}
+	for name := range f.roles {
+		resources = append(resources, "iam-role "+name)
+	}
+	for name := range f.clusters {
+		resources = append(resources, "eks-cluster "+name)
+	}
+	for name := range f.nodeGroups {
+		resources = append(resources, "node-group "+name)
+	}
+	for name := range f.loadBalancers {
+		resources = append(resources, "load-balancer "+name)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// count counts the resources of a kind left, the kind is the prefix of the entries returned by remaining
+func (f *fakeAWS) count(kind string) int {
+	n := 0
+	for _, resource := range f.remaining() {
+		if strings.HasPrefix(resource, kind+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeAWS) newID(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%08x", prefix, f.nextID)
+}
+
+func notFound(code, id string) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf("the ID '%s' does not exist", id)}
+}
+
+func dependencyViolation(id string) error {
+	return &smithy.GenericAPIError{Code: "DependencyViolation", Message: fmt.Sprintf("resource %s has a dependent object", id)}
+}
+
+func tagsOf(specs []ec2Types.TagSpecification) []ec2Types.Tag {
+	var tags []ec2Types.Tag
+	for _, spec := range specs {
+		tags = append(tags, spec.Tags...)
+	}
+	return tags
+}
+
+// matchFilters matches a resource against the filters of a Describe operation,
+// attributes are the values of the filters that are not tag filters, e.g. vpc-id
+func matchFilters(filters []ec2Types.Filter, tags []ec2Types.Tag, attributes map[string][]string) bool {
+	for _, filter := range filters {
+		name := aws.ToString(filter.Name)
+		var values []string
+		switch {
+		case strings.HasPrefix(name, "tag:"):
+			for _, tag := range tags {
+				if aws.ToString(tag.Key) == strings.TrimPrefix(name, "tag:") {
+					values = append(values, aws.ToString(tag.Value))
+				}
+			}
+		case name == "tag-key":
+			for _, tag := range tags {
+				values = append(values, aws.ToString(tag.Key))
+			}
+		default:
+			values = attributes[name]
+		}
+		if !slices.ContainsFunc(values, func(value string) bool { return slices.Contains(filter.Values, value) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func isMainRouteTable(rt *ec2Types.RouteTable) bool {
+	return slices.ContainsFunc(rt.Associations, func(assoc ec2Types.RouteTableAssociation) bool {
+		return aws.ToBool(assoc.Main)
+	})
+}
+
+func samePermission(a, b ec2Types.IpPermission) bool {
+	groupsOf := func(p ec2Types.IpPermission) []string {
+		var ids []string
+		for _, pair := range p.UserIdGroupPairs {
+			ids = append(ids, aws.ToString(pair.GroupId))
+		}
+		for _, r := range p.IpRanges {
+			ids = append(ids, aws.ToString(r.CidrIp))
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	return aws.ToString(a.IpProtocol) == aws.ToString(b.IpProtocol) && slices.Equal(groupsOf(a), groupsOf(b))
+}
+
+// revokePermissions removes the rules from a list of rules, it fails when one of them does not exist
+func revokePermissions(rules []ec2Types.IpPermission, revoked []ec2Types.IpPermission, groupID string) ([]ec2Types.IpPermission, error) {
+	for _, permission := range revoked {
+		index := slices.IndexFunc(rules, func(rule ec2Types.IpPermission) bool { return samePermission(rule, permission) })
+		if index < 0 {
+			return nil, notFound("InvalidPermission.NotFound", groupID)
+		}
+		rules = slices.Delete(slices.Clone(rules), index, index+1)
+	}
+	return rules, nil
+}
+
+func (f *fakeAWS) AssociateRouteTable(_ context.Context, params *ec2.AssociateRouteTableInput, _ ...func(*ec2.Options)) (*ec2.AssociateRouteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["AssociateRouteTable"]; err != nil {
+		return nil, err
+	}
+
+	rt, ok := f.routeTables[aws.ToString(params.RouteTableId)]
+	if !ok {
+		return nil, notFound("InvalidRouteTableID.NotFound", aws.ToString(params.RouteTableId))
+	}
+	if _, ok := f.subnets[aws.ToString(params.SubnetId)]; !ok {
+		return nil, notFound("InvalidSubnetID.NotFound", aws.ToString(params.SubnetId))
+	}
+	assocID := f.newID("rtbassoc")
+	rt.Associations = append(rt.Associations, ec2Types.RouteTableAssociation{
+		RouteTableAssociationId: aws.String(assocID),
+		RouteTableId:            rt.RouteTableId,
+		SubnetId:                params.SubnetId,
+		Main:                    aws.Bool(false),
+	})
+	return &ec2.AssociateRouteTableOutput{AssociationId: aws.String(assocID)}, nil
+}
+
+func (f *fakeAWS) AttachInternetGateway(_ context.Context, params *ec2.AttachInternetGatewayInput, _ ...func(*ec2.Options)) (*ec2.AttachInternetGatewayOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["AttachInternetGateway"]; err != nil {
+		return nil, err
+	}
+
+	igw, ok := f.internetGateways[aws.ToString(params.InternetGatewayId)]
+	if !ok {
+		return nil, notFound("InvalidInternetGatewayID.NotFound", aws.ToString(params.InternetGatewayId))
+	}
+	if _, ok := f.vpcs[aws.ToString(params.VpcId)]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", aws.ToString(params.VpcId))
+	}
+	if len(igw.Attachments) > 0 {
+		return nil, &smithy.GenericAPIError{Code: "Resource.AlreadyAssociated", Message: "the internet gateway is already attached"}
+	}
+	igw.Attachments = []ec2Types.InternetGatewayAttachment{{VpcId: params.VpcId, State: ec2Types.AttachmentStatusAttached}}
+	return &ec2.AttachInternetGatewayOutput{}, nil
+}
+
+func (f *fakeAWS) AuthorizeSecurityGroupIngress(_ context.Context, params *ec2.AuthorizeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["AuthorizeSecurityGroupIngress"]; err != nil {
+		return nil, err
+	}
+
+	sg, ok := f.securityGroups[aws.ToString(params.GroupId)]
+	if !ok {
+		return nil, notFound("InvalidGroup.NotFound", aws.ToString(params.GroupId))
+	}
+	for _, permission := range params.IpPermissions {
+		for _, pair := range permission.UserIdGroupPairs {
+			if _, ok := f.securityGroups[aws.ToString(pair.GroupId)]; !ok {
+				return nil, notFound("InvalidGroup.NotFound", aws.ToString(pair.GroupId))
+			}
+		}
+	}
+	sg.IpPermissions = append(sg.IpPermissions, params.IpPermissions...)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{Return: aws.Bool(true)}, nil
+}
+
+func (f *fakeAWS) CreateInternetGateway(_ context.Context, params *ec2.CreateInternetGatewayInput, _ ...func(*ec2.Options)) (*ec2.CreateInternetGatewayOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateInternetGateway"]; err != nil {
+		return nil, err
+	}
+
+	igw := &ec2Types.InternetGateway{
+		InternetGatewayId: aws.String(f.newID("igw")),
+		Tags:              tagsOf(params.TagSpecifications),
+	}
+	f.internetGateways[*igw.InternetGatewayId] = igw
+	return &ec2.CreateInternetGatewayOutput{InternetGateway: igw}, nil
+}
+
+func (f *fakeAWS) CreateLaunchTemplate(_ context.Context, params *ec2.CreateLaunchTemplateInput, _ ...func(*ec2.Options)) (*ec2.CreateLaunchTemplateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateLaunchTemplate"]; err != nil {
+		return nil, err
+	}
+
+	for _, template := range f.launchTemplates {
+		if aws.ToString(template.LaunchTemplateName) == aws.ToString(params.LaunchTemplateName) {
+			return nil, &smithy.GenericAPIError{Code: "InvalidLaunchTemplateName.AlreadyExistsException", Message: "launch template name already in use"}
+		}
+	}
+	template := &ec2Types.LaunchTemplate{
+		LaunchTemplateId:   aws.String(f.newID("lt")),
+		LaunchTemplateName: params.LaunchTemplateName,
+		Tags:               tagsOf(params.TagSpecifications),
+	}
+	f.launchTemplates[*template.LaunchTemplateId] = template
+	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: template}, nil
+}
+
+func (f *fakeAWS) CreateRoute(_ context.Context, params *ec2.CreateRouteInput, _ ...func(*ec2.Options)) (*ec2.CreateRouteOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateRoute"]; err != nil {
+		return nil, err
+	}
+
+	rt, ok := f.routeTables[aws.ToString(params.RouteTableId)]
+	if !ok {
+		return nil, notFound("InvalidRouteTableID.NotFound", aws.ToString(params.RouteTableId))
+	}
+	if _, ok := f.internetGateways[aws.ToString(params.GatewayId)]; !ok {
+		return nil, notFound("InvalidInternetGatewayID.NotFound", aws.ToString(params.GatewayId))
+	}
+	rt.Routes = append(rt.Routes, ec2Types.Route{
+		DestinationCidrBlock:     params.DestinationCidrBlock,
+		DestinationIpv6CidrBlock: params.DestinationIpv6CidrBlock,
+		GatewayId:                params.GatewayId,
+	})
+	return &ec2.CreateRouteOutput{Return: aws.Bool(true)}, nil
+}
+
+func (f *fakeAWS) CreateRouteTable(_ context.Context, params *ec2.CreateRouteTableInput, _ ...func(*ec2.Options)) (*ec2.CreateRouteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateRouteTable"]; err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.vpcs[aws.ToString(params.VpcId)]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", aws.ToString(params.VpcId))
+	}
+	rt := &ec2Types.RouteTable{
+		RouteTableId: aws.String(f.newID("rtb")),
+		VpcId:        params.VpcId,
+		Tags:         tagsOf(params.TagSpecifications),
+	}
+	f.routeTables[*rt.RouteTableId] = rt
+	return &ec2.CreateRouteTableOutput{RouteTable: rt}, nil
+}
+
+func (f *fakeAWS) CreateSecurityGroup(_ context.Context, params *ec2.CreateSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateSecurityGroup"]; err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.vpcs[aws.ToString(params.VpcId)]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", aws.ToString(params.VpcId))
+	}
+	sg := f.newSecurityGroup(aws.ToString(params.VpcId), aws.ToString(params.GroupName), tagsOf(params.TagSpecifications))
+	return &ec2.CreateSecurityGroupOutput{GroupId: sg.GroupId}, nil
+}
+
+// newSecurityGroup adds a security group, which allows all outbound traffic as the security groups of AWS do
+func (f *fakeAWS) newSecurityGroup(vpcID, name string, tags []ec2Types.Tag) *ec2Types.SecurityGroup {
+	sg := &ec2Types.SecurityGroup{
+		GroupId:   aws.String(f.newID("sg")),
+		GroupName: aws.String(name),
+		VpcId:     aws.String(vpcID),
+		Tags:      tags,
+		IpPermissionsEgress: []ec2Types.IpPermission{
+			{IpProtocol: aws.String("-1"), IpRanges: []ec2Types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		},
+	}
+	f.securityGroups[*sg.GroupId] = sg
+	return sg
+}
+
+func (f *fakeAWS) CreateSubnet(_ context.Context, params *ec2.CreateSubnetInput, _ ...func(*ec2.Options)) (*ec2.CreateSubnetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateSubnet"]; err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.vpcs[aws.ToString(params.VpcId)]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", aws.ToString(params.VpcId))
+	}
+	subnet := &ec2Types.Subnet{
+		SubnetId:         aws.String(f.newID("subnet")),
+		VpcId:            params.VpcId,
+		CidrBlock:        params.CidrBlock,
+		AvailabilityZone: params.AvailabilityZone,
+		Tags:             tagsOf(params.TagSpecifications),
+	}
+	if params.Ipv6CidrBlock != nil {
+		subnet.Ipv6CidrBlockAssociationSet = []ec2Types.SubnetIpv6CidrBlockAssociation{{Ipv6CidrBlock: params.Ipv6CidrBlock}}
+	}
+	f.subnets[*subnet.SubnetId] = subnet
+	return &ec2.CreateSubnetOutput{Subnet: subnet}, nil
+}
+
+// CreateVpc creates a VPC along with its main route table and its default security group, like AWS does
+func (f *fakeAWS) CreateVpc(_ context.Context, params *ec2.CreateVpcInput, _ ...func(*ec2.Options)) (*ec2.CreateVpcOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateVpc"]; err != nil {
+		return nil, err
+	}
+
+	vpc := &ec2Types.Vpc{
+		VpcId:     aws.String(f.newID("vpc")),
+		CidrBlock: params.CidrBlock,
+		Tags:      tagsOf(params.TagSpecifications),
+	}
+	if aws.ToBool(params.AmazonProvidedIpv6CidrBlock) {
+		vpc.Ipv6CidrBlockAssociationSet = []ec2Types.VpcIpv6CidrBlockAssociation{
+			{
+				Ipv6CidrBlock:      aws.String("2600:1f18:1234:5600::/56"),
+				Ipv6CidrBlockState: &ec2Types.VpcCidrBlockState{State: ec2Types.VpcCidrBlockStateCodeAssociated},
+			},
+		}
+	}
+	f.vpcs[*vpc.VpcId] = vpc
+
+	mainRouteTableID := f.newID("rtb")
+	f.routeTables[mainRouteTableID] = &ec2Types.RouteTable{
+		RouteTableId: aws.String(mainRouteTableID),
+		VpcId:        vpc.VpcId,
+		Associations: []ec2Types.RouteTableAssociation{
+			{RouteTableAssociationId: aws.String(f.newID("rtbassoc")), RouteTableId: aws.String(mainRouteTableID), Main: aws.Bool(true)},
+		},
+	}
+	f.newSecurityGroup(*vpc.VpcId, "default", nil)
+	return &ec2.CreateVpcOutput{Vpc: vpc}, nil
+}
+
+func (f *fakeAWS) DeleteInternetGateway(_ context.Context, params *ec2.DeleteInternetGatewayInput, _ ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteInternetGateway"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.InternetGatewayId)
+	igw, ok := f.internetGateways[id]
+	if !ok {
+		return nil, notFound("InvalidInternetGatewayID.NotFound", id)
+	}
+	if len(igw.Attachments) > 0 {
+		return nil, dependencyViolation(id)
+	}
+	delete(f.internetGateways, id)
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+func (f *fakeAWS) DeleteLaunchTemplate(_ context.Context, params *ec2.DeleteLaunchTemplateInput, _ ...func(*ec2.Options)) (*ec2.DeleteLaunchTemplateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteLaunchTemplate"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.LaunchTemplateId)
+	template, ok := f.launchTemplates[id]
+	if !ok {
+		return nil, notFound("InvalidLaunchTemplateId.NotFound", id)
+	}
+	delete(f.launchTemplates, id)
+	return &ec2.DeleteLaunchTemplateOutput{LaunchTemplate: template}, nil
+}
+
+func (f *fakeAWS) DeleteRouteTable(_ context.Context, params *ec2.DeleteRouteTableInput, _ ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteRouteTable"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.RouteTableId)
+	rt, ok := f.routeTables[id]
+	if !ok {
+		return nil, notFound("InvalidRouteTableID.NotFound", id)
+	}
+	if len(rt.Associations) > 0 {
+		return nil, dependencyViolation(id)
+	}
+	delete(f.routeTables, id)
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+func (f *fakeAWS) DeleteSecurityGroup(_ context.Context, params *ec2.DeleteSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteSecurityGroup"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.GroupId)
+	if _, ok := f.securityGroups[id]; !ok {
+		return nil, notFound("InvalidGroup.NotFound", id)
+	}
+	for _, sg := range f.securityGroups {
+		for _, permission := range slices.Concat(sg.IpPermissions, sg.IpPermissionsEgress) {
+			for _, pair := range permission.UserIdGroupPairs {
+				if aws.ToString(pair.GroupId) == id && aws.ToString(sg.GroupId) != id {
+					return nil, dependencyViolation(id)
+				}
+			}
+		}
+	}
+	for _, cluster := range f.clusters {
+		if slices.Contains(cluster.ResourcesVpcConfig.SecurityGroupIds, id) {
+			return nil, dependencyViolation(id)
+		}
+	}
+	delete(f.securityGroups, id)
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+func (f *fakeAWS) DeleteSubnet(_ context.Context, params *ec2.DeleteSubnetInput, _ ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteSubnet"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.SubnetId)
+	if _, ok := f.subnets[id]; !ok {
+		return nil, notFound("InvalidSubnetID.NotFound", id)
+	}
+	for _, cluster := range f.clusters {
+		if slices.Contains(cluster.ResourcesVpcConfig.SubnetIds, id) {
+			return nil, dependencyViolation(id)
+		}
+	}
+	delete(f.subnets, id)
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+// DeleteVpc deletes a VPC along with its main route table and its default security group,
+// it fails while anything else is left in the VPC
+func (f *fakeAWS) DeleteVpc(_ context.Context, params *ec2.DeleteVpcInput, _ ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteVpc"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.VpcId)
+	if _, ok := f.vpcs[id]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", id)
+	}
+	for _, subnet := range f.subnets {
+		if aws.ToString(subnet.VpcId) == id {
+			return nil, dependencyViolation(id)
+		}
+	}
+	for _, igw := range f.internetGateways {
+		for _, attachment := range igw.Attachments {
+			if aws.ToString(attachment.VpcId) == id {
+				return nil, dependencyViolation(id)
+			}
+		}
+	}
+	for _, rt := range f.routeTables {
+		if aws.ToString(rt.VpcId) == id && !isMainRouteTable(rt) {
+			return nil, dependencyViolation(id)
+		}
+	}
+	for _, sg := range f.securityGroups {
+		if aws.ToString(sg.VpcId) == id && aws.ToString(sg.GroupName) != "default" {
+			return nil, dependencyViolation(id)
+		}
+	}
+
+	for rtID, rt := range f.routeTables {
+		if aws.ToString(rt.VpcId) == id {
+			delete(f.routeTables, rtID)
+		}
+	}
+	for sgID, sg := range f.securityGroups {
+		if aws.ToString(sg.VpcId) == id {
+			delete(f.securityGroups, sgID)
+		}
+	}
+	delete(f.vpcs, id)
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+func (f *fakeAWS) DescribeAvailabilityZones(_ context.Context, _ *ec2.DescribeAvailabilityZonesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeAvailabilityZones"]; err != nil {
+		return nil, err
+	}
+
+	return &ec2.DescribeAvailabilityZonesOutput{
+		AvailabilityZones: []ec2Types.AvailabilityZone{
+			{ZoneName: aws.String("us-east-1a"), State: ec2Types.AvailabilityZoneStateAvailable},
+			{ZoneName: aws.String("us-east-1b"), State: ec2Types.AvailabilityZoneStateImpaired},
+			{ZoneName: aws.String("us-east-1c"), State: ec2Types.AvailabilityZoneStateAvailable},
+			{ZoneName: aws.String("us-east-1d"), State: ec2Types.AvailabilityZoneStateAvailable},
+		},
+	}, nil
+}
+
+func (f *fakeAWS) DescribeInternetGateways(_ context.Context, params *ec2.DescribeInternetGatewaysInput, _ ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeInternetGateways"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeInternetGatewaysOutput{}
+	for _, igw := range f.internetGateways {
+		var vpcIDs []string
+		for _, attachment := range igw.Attachments {
+			vpcIDs = append(vpcIDs, aws.ToString(attachment.VpcId))
+		}
+		if matchFilters(params.Filters, igw.Tags, map[string][]string{"attachment.vpc-id": vpcIDs}) {
+			output.InternetGateways = append(output.InternetGateways, *igw)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DescribeLaunchTemplates(_ context.Context, params *ec2.DescribeLaunchTemplatesInput, _ ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeLaunchTemplates"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeLaunchTemplatesOutput{}
+	for _, template := range f.launchTemplates {
+		attributes := map[string][]string{"launch-template-name": {aws.ToString(template.LaunchTemplateName)}}
+		if matchFilters(params.Filters, template.Tags, attributes) {
+			output.LaunchTemplates = append(output.LaunchTemplates, *template)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DescribeRouteTables(_ context.Context, params *ec2.DescribeRouteTablesInput, _ ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeRouteTables"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeRouteTablesOutput{}
+	for _, rt := range f.routeTables {
+		if matchFilters(params.Filters, rt.Tags, map[string][]string{"vpc-id": {aws.ToString(rt.VpcId)}}) {
+			output.RouteTables = append(output.RouteTables, *rt)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DescribeSecurityGroups(_ context.Context, params *ec2.DescribeSecurityGroupsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeSecurityGroups"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSecurityGroupsOutput{}
+	for _, sg := range f.securityGroups {
+		if matchFilters(params.Filters, sg.Tags, map[string][]string{"vpc-id": {aws.ToString(sg.VpcId)}}) {
+			output.SecurityGroups = append(output.SecurityGroups, *sg)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DescribeSubnets(_ context.Context, params *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeSubnets"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, id := range params.SubnetIds {
+		if _, ok := f.subnets[id]; !ok {
+			return nil, notFound("InvalidSubnetID.NotFound", id)
+		}
+	}
+	for id, subnet := range f.subnets {
+		if len(params.SubnetIds) > 0 && !slices.Contains(params.SubnetIds, id) {
+			continue
+		}
+		if matchFilters(params.Filters, subnet.Tags, map[string][]string{"vpc-id": {aws.ToString(subnet.VpcId)}}) {
+			output.Subnets = append(output.Subnets, *subnet)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DescribeVpcs(_ context.Context, params *ec2.DescribeVpcsInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeVpcs"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeVpcsOutput{}
+	for _, id := range params.VpcIds {
+		if _, ok := f.vpcs[id]; !ok {
+			return nil, notFound("InvalidVpcID.NotFound", id)
+		}
+	}
+	for id, vpc := range f.vpcs {
+		if len(params.VpcIds) > 0 && !slices.Contains(params.VpcIds, id) {
+			continue
+		}
+		if matchFilters(params.Filters, vpc.Tags, map[string][]string{"vpc-id": {id}}) {
+			output.Vpcs = append(output.Vpcs, *vpc)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) DetachInternetGateway(_ context.Context, params *ec2.DetachInternetGatewayInput, _ ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DetachInternetGateway"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.InternetGatewayId)
+	igw, ok := f.internetGateways[id]
+	if !ok {
+		return nil, notFound("InvalidInternetGatewayID.NotFound", id)
+	}
+	if len(igw.Attachments) == 0 || aws.ToString(igw.Attachments[0].VpcId) != aws.ToString(params.VpcId) {
+		return nil, &smithy.GenericAPIError{Code: "Gateway.NotAttached", Message: fmt.Sprintf("the internet gateway %s is not attached", id)}
+	}
+	igw.Attachments = nil
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+func (f *fakeAWS) DisassociateRouteTable(_ context.Context, params *ec2.DisassociateRouteTableInput, _ ...func(*ec2.Options)) (*ec2.DisassociateRouteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DisassociateRouteTable"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.AssociationId)
+	for _, rt := range f.routeTables {
+		index := slices.IndexFunc(rt.Associations, func(assoc ec2Types.RouteTableAssociation) bool {
+			return aws.ToString(assoc.RouteTableAssociationId) == id
+		})
+		if index >= 0 {
+			rt.Associations = slices.Delete(slices.Clone(rt.Associations), index, index+1)
+			return &ec2.DisassociateRouteTableOutput{}, nil
+		}
+	}
+	return nil, notFound("InvalidAssociationID.NotFound", id)
+}
+
+func (f *fakeAWS) ModifySubnetAttribute(_ context.Context, params *ec2.ModifySubnetAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["ModifySubnetAttribute"]; err != nil {
+		return nil, err
+	}
+
+	subnet, ok := f.subnets[aws.ToString(params.SubnetId)]
+	if !ok {
+		return nil, notFound("InvalidSubnetID.NotFound", aws.ToString(params.SubnetId))
+	}
+	if params.MapPublicIpOnLaunch != nil {
+		subnet.MapPublicIpOnLaunch = params.MapPublicIpOnLaunch.Value
+	}
+	if params.AssignIpv6AddressOnCreation != nil {
+		subnet.AssignIpv6AddressOnCreation = params.AssignIpv6AddressOnCreation.Value
+	}
+	return &ec2.ModifySubnetAttributeOutput{}, nil
+}
+
+func (f *fakeAWS) ModifyVpcAttribute(_ context.Context, params *ec2.ModifyVpcAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifyVpcAttributeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["ModifyVpcAttribute"]; err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.vpcs[aws.ToString(params.VpcId)]; !ok {
+		return nil, notFound("InvalidVpcID.NotFound", aws.ToString(params.VpcId))
+	}
+	return &ec2.ModifyVpcAttributeOutput{}, nil
+}
+
+func (f *fakeAWS) RevokeSecurityGroupEgress(_ context.Context, params *ec2.RevokeSecurityGroupEgressInput, _ ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["RevokeSecurityGroupEgress"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.GroupId)
+	sg, ok := f.securityGroups[id]
+	if !ok {
+		return nil, notFound("InvalidGroup.NotFound", id)
+	}
+	rules, err := revokePermissions(sg.IpPermissionsEgress, params.IpPermissions, id)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissionsEgress = rules
+	return &ec2.RevokeSecurityGroupEgressOutput{Return: aws.Bool(true)}, nil
+}
+
+func (f *fakeAWS) RevokeSecurityGroupIngress(_ context.Context, params *ec2.RevokeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["RevokeSecurityGroupIngress"]; err != nil {
+		return nil, err
+	}
+
+	id := aws.ToString(params.GroupId)
+	sg, ok := f.securityGroups[id]
+	if !ok {
+		return nil, notFound("InvalidGroup.NotFound", id)
+	}
+	rules, err := revokePermissions(sg.IpPermissions, params.IpPermissions, id)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissions = rules
+	return &ec2.RevokeSecurityGroupIngressOutput{Return: aws.Bool(true)}, nil
+}
+
+// CreateCluster creates a cluster that is active right away
+func (f *fakeAWS) CreateCluster(_ context.Context, params *eks.CreateClusterInput, _ ...func(*eks.Options)) (*eks.CreateClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateCluster"]; err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	if _, ok := f.clusters[name]; ok {
+		return nil, &types.ResourceInUseException{Message: aws.String(fmt.Sprintf("cluster already exists with name: %s", name))}
+	}
+	if _, ok := f.roles[roleNameOf(aws.ToString(params.RoleArn))]; !ok {
+		return nil, &types.InvalidParameterException{Message: aws.String("role with arn: " + aws.ToString(params.RoleArn) + " could not be assumed")}
+	}
+	subnetIDs := params.ResourcesVpcConfig.SubnetIds
+	if len(subnetIDs) < 2 {
+		return nil, &types.InvalidParameterException{Message: aws.String("subnets in at least 2 availability zones are required")}
+	}
+	subnet, ok := f.subnets[subnetIDs[0]]
+	if !ok {
+		return nil, &types.InvalidParameterException{Message: aws.String("the subnet ID '" + subnetIDs[0] + "' does not exist")}
+	}
+
+	networkConfig := &types.KubernetesNetworkConfigResponse{IpFamily: types.IpFamilyIpv4}
+	if params.KubernetesNetworkConfig != nil && params.KubernetesNetworkConfig.IpFamily == types.IpFamilyIpv6 {
+		networkConfig = &types.KubernetesNetworkConfigResponse{IpFamily: types.IpFamilyIpv6, ServiceIpv6Cidr: aws.String("fd12:3456:789a::/108")}
+	} else if params.KubernetesNetworkConfig != nil {
+		networkConfig.ServiceIpv4Cidr = params.KubernetesNetworkConfig.ServiceIpv4Cidr
+	}
+
+	cluster := &types.Cluster{
+		Name:                    params.Name,
+		Arn:                     aws.String("arn:aws:eks:us-east-1:123456789012:cluster/" + name),
+		Version:                 params.Version,
+		RoleArn:                 params.RoleArn,
+		Status:                  types.ClusterStatusActive,
+		Endpoint:                aws.String(fmt.Sprintf("https://%s.gr7.us-east-1.eks.amazonaws.com", strings.ToUpper(f.newID("endpoint")))),
+		CertificateAuthority:    &types.Certificate{Data: aws.String(base64.StdEncoding.EncodeToString([]byte("fake certificate authority")))},
+		KubernetesNetworkConfig: networkConfig,
+		ResourcesVpcConfig: &types.VpcConfigResponse{
+			VpcId:            subnet.VpcId,
+			SubnetIds:        subnetIDs,
+			SecurityGroupIds: params.ResourcesVpcConfig.SecurityGroupIds,
+		},
+		Tags: params.Tags,
+	}
+	f.clusters[name] = cluster
+	return &eks.CreateClusterOutput{Cluster: cluster}, nil
+}
+
+// CreateNodegroup creates a node group that is active right away
+func (f *fakeAWS) CreateNodegroup(_ context.Context, params *eks.CreateNodegroupInput, _ ...func(*eks.Options)) (*eks.CreateNodegroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateNodegroup"]; err != nil {
+		return nil, err
+	}
+
+	clusterName := aws.ToString(params.ClusterName)
+	if _, ok := f.clusters[clusterName]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no cluster found for name: " + clusterName)}
+	}
+	key := clusterName + "/" + aws.ToString(params.NodegroupName)
+	if _, ok := f.nodeGroups[key]; ok {
+		return nil, &types.ResourceInUseException{Message: aws.String("nodegroup already exists")}
+	}
+	if _, ok := f.launchTemplates[aws.ToString(params.LaunchTemplate.Id)]; !ok {
+		return nil, &types.InvalidParameterException{Message: aws.String("launch template " + aws.ToString(params.LaunchTemplate.Id) + " does not exist")}
+	}
+	nodeGroup := &types.Nodegroup{
+		ClusterName:    params.ClusterName,
+		NodegroupName:  params.NodegroupName,
+		NodeRole:       params.NodeRole,
+		Subnets:        params.Subnets,
+		ScalingConfig:  params.ScalingConfig,
+		Status:         types.NodegroupStatusActive,
+		LaunchTemplate: params.LaunchTemplate,
+	}
+	f.nodeGroups[key] = nodeGroup
+	return &eks.CreateNodegroupOutput{Nodegroup: nodeGroup}, nil
+}
+
+func (f *fakeAWS) DeleteCluster(_ context.Context, params *eks.DeleteClusterInput, _ ...func(*eks.Options)) (*eks.DeleteClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteCluster"]; err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	cluster, ok := f.clusters[name]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no cluster found for name: " + name)}
+	}
+	for _, nodeGroup := range f.nodeGroups {
+		if aws.ToString(nodeGroup.ClusterName) == name {
+			return nil, &types.ResourceInUseException{Message: aws.String("cluster has nodegroups attached")}
+		}
+	}
+	delete(f.clusters, name)
+	return &eks.DeleteClusterOutput{Cluster: cluster}, nil
+}
+
+func (f *fakeAWS) DeleteNodegroup(_ context.Context, params *eks.DeleteNodegroupInput, _ ...func(*eks.Options)) (*eks.DeleteNodegroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteNodegroup"]; err != nil {
+		return nil, err
+	}
+
+	key := aws.ToString(params.ClusterName) + "/" + aws.ToString(params.NodegroupName)
+	nodeGroup, ok := f.nodeGroups[key]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no node group found for name: " + aws.ToString(params.NodegroupName))}
+	}
+	delete(f.nodeGroups, key)
+	return &eks.DeleteNodegroupOutput{Nodegroup: nodeGroup}, nil
+}
+
+func (f *fakeAWS) DescribeCluster(_ context.Context, params *eks.DescribeClusterInput, _ ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeCluster"]; err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.Name)
+	cluster, ok := f.clusters[name]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no cluster found for name: " + name)}
+	}
+	return &eks.DescribeClusterOutput{Cluster: cluster}, nil
+}
+
+func (f *fakeAWS) DescribeNodegroup(_ context.Context, params *eks.DescribeNodegroupInput, _ ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeNodegroup"]; err != nil {
+		return nil, err
+	}
+
+	key := aws.ToString(params.ClusterName) + "/" + aws.ToString(params.NodegroupName)
+	nodeGroup, ok := f.nodeGroups[key]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("no node group found for name: " + aws.ToString(params.NodegroupName))}
+	}
+	return &eks.DescribeNodegroupOutput{Nodegroup: nodeGroup}, nil
+}
+
+func noSuchRole(name string) error {
+	return &iamTypes.NoSuchEntityException{Message: aws.String(fmt.Sprintf("the role with name %s cannot be found", name))}
+}
+
+func (f *fakeAWS) AttachRolePolicy(_ context.Context, params *iam.AttachRolePolicyInput, _ ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["AttachRolePolicy"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	if !slices.Contains(role.managedPolicies, aws.ToString(params.PolicyArn)) {
+		role.managedPolicies = append(role.managedPolicies, aws.ToString(params.PolicyArn))
+	}
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (f *fakeAWS) CreateRole(_ context.Context, params *iam.CreateRoleInput, _ ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["CreateRole"]; err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.RoleName)
+	if _, ok := f.roles[name]; ok {
+		return nil, &iamTypes.EntityAlreadyExistsException{Message: aws.String(fmt.Sprintf("role with name %s already exists", name))}
+	}
+	role := &fakeRole{
+		arn:            "arn:aws:iam::123456789012:role/" + name,
+		inlinePolicies: map[string]string{},
+	}
+	f.roles[name] = role
+	return &iam.CreateRoleOutput{Role: &iamTypes.Role{RoleName: params.RoleName, Arn: aws.String(role.arn)}}, nil
+}
+
+func (f *fakeAWS) DeleteRole(_ context.Context, params *iam.DeleteRoleInput, _ ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteRole"]; err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(params.RoleName)
+	role, ok := f.roles[name]
+	if !ok {
+		return nil, noSuchRole(name)
+	}
+	if len(role.managedPolicies) > 0 || len(role.inlinePolicies) > 0 {
+		return nil, &iamTypes.DeleteConflictException{Message: aws.String("cannot delete entity, must detach all policies first")}
+	}
+	delete(f.roles, name)
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func (f *fakeAWS) DeleteRolePolicy(_ context.Context, params *iam.DeleteRolePolicyInput, _ ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DeleteRolePolicy"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	if _, ok := role.inlinePolicies[aws.ToString(params.PolicyName)]; !ok {
+		return nil, &iamTypes.NoSuchEntityException{Message: aws.String("the role policy with name " + aws.ToString(params.PolicyName) + " cannot be found")}
+	}
+	delete(role.inlinePolicies, aws.ToString(params.PolicyName))
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func (f *fakeAWS) DetachRolePolicy(_ context.Context, params *iam.DetachRolePolicyInput, _ ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DetachRolePolicy"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	index := slices.Index(role.managedPolicies, aws.ToString(params.PolicyArn))
+	if index < 0 {
+		return nil, &iamTypes.NoSuchEntityException{Message: aws.String("policy " + aws.ToString(params.PolicyArn) + " was not found")}
+	}
+	role.managedPolicies = slices.Delete(role.managedPolicies, index, index+1)
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (f *fakeAWS) ListAttachedRolePolicies(_ context.Context, params *iam.ListAttachedRolePoliciesInput, _ ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["ListAttachedRolePolicies"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	output := &iam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range role.managedPolicies {
+		output.AttachedPolicies = append(output.AttachedPolicies, iamTypes.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	return output, nil
+}
+
+func (f *fakeAWS) ListRolePolicies(_ context.Context, params *iam.ListRolePoliciesInput, _ ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["ListRolePolicies"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	output := &iam.ListRolePoliciesOutput{}
+	for name := range role.inlinePolicies {
+		output.PolicyNames = append(output.PolicyNames, name)
+	}
+	sort.Strings(output.PolicyNames)
+	return output, nil
+}
+
+func (f *fakeAWS) PutRolePolicy(_ context.Context, params *iam.PutRolePolicyInput, _ ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["PutRolePolicy"]; err != nil {
+		return nil, err
+	}
+
+	role, ok := f.roles[aws.ToString(params.RoleName)]
+	if !ok {
+		return nil, noSuchRole(aws.ToString(params.RoleName))
+	}
+	role.inlinePolicies[aws.ToString(params.PolicyName)] = aws.ToString(params.PolicyDocument)
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+// addLoadBalancer adds a load balancer of a cluster, named after the ARN for network load balancers, that is deleted
+// after being listed pollsLeft times, or never when pollsLeft is negative
+func (f *fakeAWS) addLoadBalancer(name string, classic bool, vpcID, clusterName string, pollsLeft int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loadBalancers[name] = &fakeLoadBalancer{
+		classic:   classic,
+		vpcID:     vpcID,
+		tags:      map[string]string{fmt.Sprintf(kubernetesTagFormat, clusterName): "owned"},
+		pollsLeft: pollsLeft,
+	}
+}
+
+// listLoadBalancers returns the load balancers of a kind sorted by name, and deletes the ones that were listed
+// enough times
+func (f *fakeAWS) listLoadBalancers(classic bool) []string {
+	var names []string
+	for name, lb := range f.loadBalancers {
+		if lb.classic != classic {
+			continue
+		}
+		if lb.pollsLeft == 0 {
+			delete(f.loadBalancers, name)
+			continue
+		}
+		if lb.pollsLeft > 0 {
+			lb.pollsLeft--
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fakeELB and fakeELBv2 expose the load balancers of the fake as classic load balancers and network load balancers,
+// since both APIs have operations of the same names
+type fakeELB struct{ *fakeAWS }
+
+type fakeELBv2 struct{ *fakeAWS }
+
+func (f fakeELB) DescribeLoadBalancers(_ context.Context, _ *elb.DescribeLoadBalancersInput, _ ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeClassicLoadBalancers"]; err != nil {
+		return nil, err
+	}
+
+	output := &elb.DescribeLoadBalancersOutput{}
+	for _, name := range f.listLoadBalancers(true) {
+		output.LoadBalancerDescriptions = append(output.LoadBalancerDescriptions, elbTypes.LoadBalancerDescription{
+			LoadBalancerName: aws.String(name),
+			VPCId:            aws.String(f.loadBalancers[name].vpcID),
+		})
+	}
+	return output, nil
+}
+
+func (f fakeELB) DescribeTags(_ context.Context, params *elb.DescribeTagsInput, _ ...func(*elb.Options)) (*elb.DescribeTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(params.LoadBalancerNames) > describeTagsBatchSize {
+		return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "too many load balancer names"}
+	}
+
+	output := &elb.DescribeTagsOutput{}
+	for _, name := range params.LoadBalancerNames {
+		lb, ok := f.loadBalancers[name]
+		if !ok || !lb.classic {
+			return nil, notFound("LoadBalancerNotFound", name)
+		}
+		f.describedTags = append(f.describedTags, name)
+		description := elbTypes.TagDescription{LoadBalancerName: aws.String(name)}
+		for key, value := range lb.tags {
+			description.Tags = append(description.Tags, elbTypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		output.TagDescriptions = append(output.TagDescriptions, description)
+	}
+	return output, nil
+}
+
+func (f fakeELBv2) DescribeLoadBalancers(_ context.Context, _ *elbv2.DescribeLoadBalancersInput, _ ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures["DescribeLoadBalancers"]; err != nil {
+		return nil, err
+	}
+
+	output := &elbv2.DescribeLoadBalancersOutput{}
+	for _, arn := range f.listLoadBalancers(false) {
+		output.LoadBalancers = append(output.LoadBalancers, elbv2Types.LoadBalancer{
+			LoadBalancerArn: aws.String(arn),
+			VpcId:           aws.String(f.loadBalancers[arn].vpcID),
+		})
+	}
+	return output, nil
+}
+
+func (f fakeELBv2) DescribeTags(_ context.Context, params *elbv2.DescribeTagsInput, _ ...func(*elbv2.Options)) (*elbv2.DescribeTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(params.ResourceArns) > describeTagsBatchSize {
+		return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "too many resource ARNs"}
+	}
+
+	output := &elbv2.DescribeTagsOutput{}
+	for _, arn := range params.ResourceArns {
+		lb, ok := f.loadBalancers[arn]
+		if !ok || lb.classic {
+			return nil, notFound("LoadBalancerNotFound", arn)
+		}
+		f.describedTags = append(f.describedTags, arn)
+		description := elbv2Types.TagDescription{ResourceArn: aws.String(arn)}
+		for key, value := range lb.tags {
+			description.Tags = append(description.Tags, elbv2Types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		output.TagDescriptions = append(output.TagDescriptions, description)
+	}
+	return output, nil
+}
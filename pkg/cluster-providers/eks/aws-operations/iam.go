@@ -8,9 +8,18 @@ import (
 	"strings"
 )
 
-func createRoles(ctx context.Context, iamClient *iam.Client, namePrefix string) (string, string, error) {
-	clusterRoleArn, err := createRole(ctx, iamClient,
-		namePrefix+"-EksClusterRole", "Allows access to other AWS service resources that are required to operate clusters managed by EKS.",
+// the roles of a cluster are named by the name of the cluster followed by these suffixes,
+// so that they are found by the name of the cluster when cleaning it up
+const (
+	clusterRoleNameSuffix = "-EksClusterRole"
+	nodeRoleNameSuffix    = "-NodeInstanceRole"
+)
+
+// createRoles creates the roles of the cluster and of the nodes, the VPC CNI on the nodes of IPv6 clusters
+// needs the permission to assign IPv6 addresses, which is not granted by the managed CNI policy
+func createRoles(ctx context.Context, iamClient iamAPI, journal *Journal, namePrefix string, ipv6 bool) (string, string, error) {
+	clusterRoleArn, err := createRole(ctx, iamClient, journal,
+		namePrefix+clusterRoleNameSuffix, "Allows access to other AWS service resources that are required to operate clusters managed by EKS.",
 		[]string{"arn:aws:iam::aws:policy/AmazonEKSClusterPolicy",
 			"arn:aws:iam::aws:policy/AmazonEKSVPCResourceController"},
 		map[string]string{
@@ -22,13 +31,17 @@ func createRoles(ctx context.Context, iamClient *iam.Client, namePrefix string)
 		return "", "", errors.Wrap(err, "error creating the IAM role for the cluster to use")
 	}
 
-	nodeRoleArn, err := createRole(ctx, iamClient,
-		namePrefix+"-NodeInstanceRole", "Allows EC2 instances to call AWS services on your behalf.",
+	var nodeInlinePolicies map[string]string
+	if ipv6 {
+		nodeInlinePolicies = map[string]string{"CNIIPv6Policy": inlinePolicyCNIIPv6}
+	}
+	nodeRoleArn, err := createRole(ctx, iamClient, journal,
+		namePrefix+nodeRoleNameSuffix, "Allows EC2 instances to call AWS services on your behalf.",
 		[]string{"arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
 			"arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
 			"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
 			"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore",
-		}, nil, trustedEntitiesEC2,
+		}, nodeInlinePolicies, trustedEntitiesEC2,
 	)
 	if err != nil {
 		return "", "", errors.Wrap(err, "error creating the IAM role for the nodegroup to use")
@@ -36,7 +49,7 @@ func createRoles(ctx context.Context, iamClient *iam.Client, namePrefix string)
 	return clusterRoleArn, nodeRoleArn, nil
 }
 
-func createRole(ctx context.Context, iamClient *iam.Client,
+func createRole(ctx context.Context, iamClient iamAPI, journal *Journal,
 	newRoleName string, newRoleDescription string, managedPolicyNames []string, inlinePolicies map[string]string, trustPolicy string) (string, error) {
 	input := &iam.CreateRoleInput{
 		RoleName:                 aws.String(newRoleName),
@@ -48,6 +61,10 @@ func createRole(ctx context.Context, iamClient *iam.Client,
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to create role %s", newRoleName)
 	}
+	// the role is recorded before attaching the policies, so that a role with some of the policies is deleted as well
+	if err := journal.record(ResourceIAMRole, aws.ToString(roleOutput.Role.Arn), ""); err != nil {
+		return "", err
+	}
 
 	for name, policy := range inlinePolicies {
 		_, err := iamClient.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
@@ -73,38 +90,49 @@ func createRole(ctx context.Context, iamClient *iam.Client,
 	return aws.ToString(roleOutput.Role.Arn), nil
 }
 
-func deleteRoles(ctx context.Context, iamClient *iam.Client, roles []string) error {
-	const splitter = ":role/"
-
-	for _, roleArn := range roles {
-		if roleArn == "" {
+// deleteRoles deletes the roles of the given names, it carries on when deleting a role fails and returns all the
+// failures. The roles that do not exist are skipped.
+func deleteRoles(ctx context.Context, iamClient iamAPI, roleNames []string) error {
+	var errs cleanupErrors
+	for _, roleName := range roleNames {
+		if roleName == "" {
 			continue
 		}
-		indexOfPrefix := strings.Index(roleArn, splitter)
-		roleName := roleArn[indexOfPrefix+len(splitter):]
-
-		err := detachManagedPolicies(ctx, iamClient, roleName)
-		if err != nil {
-			return err
-		}
+		errs.add(deleteRole(ctx, iamClient, roleName))
+	}
+	return errs.err()
+}
 
-		err = deleteInlinePolicies(ctx, iamClient, roleName)
-		if err != nil {
-			return err
-		}
+func deleteRole(ctx context.Context, iamClient iamAPI, roleName string) error {
+	err := detachManagedPolicies(ctx, iamClient, roleName)
+	if err != nil {
+		return err
+	}
 
-		_, err = iamClient.DeleteRole(ctx, &iam.DeleteRoleInput{
-			RoleName: aws.String(roleName),
-		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to delete IAM role %s", roleArn)
-		}
+	err = deleteInlinePolicies(ctx, iamClient, roleName)
+	if err != nil {
+		return err
 	}
 
+	_, err = iamClient.DeleteRole(ctx, &iam.DeleteRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete IAM role %s", roleName)
+	}
 	return nil
 }
 
-func detachManagedPolicies(ctx context.Context, client *iam.Client, roleName string) error {
+// roleNameOf returns the name of a role from its ARN
+func roleNameOf(roleArn string) string {
+	const splitter = ":role/"
+	if index := strings.Index(roleArn, splitter); index >= 0 {
+		return roleArn[index+len(splitter):]
+	}
+	return roleArn
+}
+
+func detachManagedPolicies(ctx context.Context, client iamAPI, roleName string) error {
 	listResp, err := client.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{
 		RoleName: aws.String(roleName),
 	})
@@ -125,7 +153,7 @@ func detachManagedPolicies(ctx context.Context, client *iam.Client, roleName str
 	return nil
 }
 
-func deleteInlinePolicies(ctx context.Context, iamClient *iam.Client, roleName string) error {
+func deleteInlinePolicies(ctx context.Context, iamClient iamAPI, roleName string) error {
 	listResp, err := iamClient.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{
 		RoleName: aws.String(roleName),
 	})
@@ -198,5 +226,28 @@ const (
             "Effect": "Allow"
         }
     ]
+}`
+	inlinePolicyCNIIPv6 = `{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Action": [
+                "ec2:AssignIpv6Addresses",
+                "ec2:DescribeInstances",
+                "ec2:DescribeTags",
+                "ec2:DescribeNetworkInterfaces",
+                "ec2:DescribeInstanceTypes"
+            ],
+            "Resource": "*",
+            "Effect": "Allow"
+        },
+        {
+            "Action": [
+                "ec2:CreateTags"
+            ],
+            "Resource": "arn:aws:ec2:*:*:network-interface/*",
+            "Effect": "Allow"
+        }
+    ]
 }`
 )
@@ -0,0 +1,213 @@
+package aws_operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	err_pkg "github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envKeyJournalDir overrides the directory that the journals are kept in, e.g. to keep them across CI steps
+const envKeyJournalDir = "EKS_JOURNAL_DIR"
+
+// ResourceKind is the kind of AWS resource recorded in a journal
+type ResourceKind string
+
+const (
+	ResourceIAMRole               ResourceKind = "iam-role"
+	ResourceVPC                   ResourceKind = "vpc"
+	ResourceInternetGateway       ResourceKind = "internet-gateway"
+	ResourceGatewayAttachment     ResourceKind = "internet-gateway-attachment"
+	ResourceRouteTable            ResourceKind = "route-table"
+	ResourceRouteTableAssociation ResourceKind = "route-table-association"
+	ResourceSubnet                ResourceKind = "subnet"
+	ResourceSecurityGroup         ResourceKind = "security-group"
+	ResourceSecurityGroupIngress  ResourceKind = "security-group-ingress"
+	ResourceCluster               ResourceKind = "eks-cluster"
+	ResourceLaunchTemplate        ResourceKind = "launch-template"
+	ResourceNodeGroup             ResourceKind = "node-group"
+)
+
+// JournalEntry is an AWS resource created for an EKS cluster
+type JournalEntry struct {
+	Kind ResourceKind `json:"kind"`
+	ID   string       `json:"id"`
+	// Parent is the resource that the resource belongs to, e.g. the VPC that an internet gateway is attached to,
+	// or the security group that an ingress rule allows traffic from
+	Parent string `json:"parent,omitempty"`
+}
+
+// Journal records the AWS resources created for an EKS cluster in the order of creation, so that they are deleted
+// in the reverse order when creating the cluster fails. The journal is written to a file on every change, so that
+// the resources of an interrupted creation can still be deleted by another process.
+type Journal struct {
+	ClusterName string         `json:"clusterName"`
+	Entries     []JournalEntry `json:"entries"`
+
+	path string
+}
+
+// JournalPath returns the file that the journal of a cluster is kept in
+func JournalPath(clusterName string) string {
+	dir := os.Getenv(envKeyJournalDir)
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "kuma-smoke", "eks-journals")
+	}
+	return filepath.Join(dir, clusterName+".json")
+}
+
+// NewJournal starts the journal of a cluster to create, it fails when the journal of an earlier creation is left
+func NewJournal(clusterName string) (*Journal, error) {
+	j := &Journal{ClusterName: clusterName, Entries: []JournalEntry{}, path: JournalPath(clusterName)}
+	if _, err := os.Stat(j.path); err == nil {
+		return nil, err_pkg.Errorf("the journal %s of an earlier creation of cluster %s is left, clean up the cluster first", j.path, clusterName)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return nil, err_pkg.Wrap(err, "failed to create the journal directory")
+	}
+	return j, j.write()
+}
+
+// LoadJournal loads the journal of a cluster, it returns nil when there is no journal,
+// which means the creation of the cluster completed or its resources were already deleted
+func LoadJournal(clusterName string) (*Journal, error) {
+	path := JournalPath(clusterName)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err_pkg.Wrapf(err, "failed to read journal %s", path)
+	}
+
+	j := &Journal{path: path}
+	if err := json.Unmarshal(content, j); err != nil {
+		return nil, err_pkg.Wrapf(err, "failed to parse journal %s", path)
+	}
+	return j, nil
+}
+
+// Path returns the file that the journal is kept in
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// record adds a created resource to the journal
+func (j *Journal) record(kind ResourceKind, id, parent string) error {
+	j.Entries = append(j.Entries, JournalEntry{Kind: kind, ID: id, Parent: parent})
+	return j.write()
+}
+
+// Complete removes the journal once the cluster is created, the resources of the cluster are found from the cluster then
+func (j *Journal) Complete() error {
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err_pkg.Wrapf(err, "failed to remove journal %s", j.path)
+	}
+	return nil
+}
+
+// Rollback deletes the recorded resources in the reverse order of creation. The deleted resources are removed from
+// the journal as it goes, so that a failed rollback can be retried, and the journal is removed when all of them are deleted.
+// Resources that are already gone are considered deleted.
+func (j *Journal) Rollback(ctx context.Context, cfg aws.Config) error {
+	return j.rollback(ctx, newAWSClients(cfg))
+}
+
+func (j *Journal) rollback(ctx context.Context, clients *awsClients) error {
+	var failed []JournalEntry
+	var errs []error
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		entry := j.Entries[i]
+		err := undo(ctx, clients, j.ClusterName, entry)
+		if err != nil && !isNotFound(err) {
+			failed = append([]JournalEntry{entry}, failed...)
+			errs = append(errs, err_pkg.Wrapf(err, "%s %s", entry.Kind, entry.ID))
+		}
+
+		j.Entries = append(append([]JournalEntry{}, j.Entries[:i]...), failed...)
+		if err := j.write(); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return err_pkg.Wrapf(errors.Join(errs...), "failed to delete %d resources of cluster %s, they are kept in journal %s",
+			len(errs), j.ClusterName, j.path)
+	}
+	return j.Complete()
+}
+
+func (j *Journal) write() error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(j.path, content, 0o600); err != nil {
+		return err_pkg.Wrapf(err, "failed to write journal %s", j.path)
+	}
+	return nil
+}
+
+func undo(ctx context.Context, clients *awsClients, clusterName string, entry JournalEntry) error {
+	ec2Client, eksClient, iamClient := clients.ec2, clients.eks, clients.iam
+	var err error
+	switch entry.Kind {
+	case ResourceIAMRole:
+		err = deleteRoles(ctx, iamClient, []string{roleNameOf(entry.ID)})
+	case ResourceVPC:
+		_, err = ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(entry.ID)})
+	case ResourceInternetGateway:
+		_, err = ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: aws.String(entry.ID)})
+	case ResourceGatewayAttachment:
+		_, err = ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+			InternetGatewayId: aws.String(entry.ID),
+			VpcId:             aws.String(entry.Parent),
+		})
+	case ResourceRouteTable:
+		_, err = ec2Client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{RouteTableId: aws.String(entry.ID)})
+	case ResourceRouteTableAssociation:
+		_, err = ec2Client.DisassociateRouteTable(ctx, &ec2.DisassociateRouteTableInput{AssociationId: aws.String(entry.ID)})
+	case ResourceSubnet:
+		_, err = ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(entry.ID)})
+	case ResourceSecurityGroup:
+		_, err = ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(entry.ID)})
+	case ResourceSecurityGroupIngress:
+		_, err = ec2Client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId: aws.String(entry.ID),
+			IpPermissions: []ec2Types.IpPermission{
+				{
+					IpProtocol:       aws.String("-1"),
+					UserIdGroupPairs: []ec2Types.UserIdGroupPair{{GroupId: aws.String(entry.Parent)}},
+				},
+			},
+		})
+	case ResourceCluster:
+		err = deleteCluster(ctx, eksClient, entry.ID)
+	case ResourceLaunchTemplate:
+		err = deleteNodeLaunchTemplate(ctx, ec2Client, entry.ID)
+	case ResourceNodeGroup:
+		_, _, err = deleteNodeGroup(ctx, eksClient, clusterName)
+	default:
+		err = err_pkg.Errorf("unknown resource kind %q", entry.Kind)
+	}
+	return err
+}
+
+// isNotFound tells whether an error of the AWS APIs means the resource does not exist, e.g. InvalidVpcID.NotFound,
+// or that there is nothing to undo, e.g. detaching an internet gateway that is not attached
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	code := apiErr.ErrorCode()
+	return strings.HasSuffix(code, ".NotFound") || strings.HasSuffix(code, "NotFoundException") ||
+		code == "NoSuchEntity" || code == "Gateway.NotAttached"
+}
@@ -9,15 +9,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"time"
 )
 
 const (
 	v1Prefix        = "k8s-aws-v1"
 	clusterIDHeader = "x-k8s-aws-id"
+	// tokenValidity is how long a token is accepted by EKS, which does not accept a longer one
+	tokenValidity = 15 * time.Minute
+	// tokenRefreshMargin is how long before expiring a token is refreshed, so that a request never carries an expired one
+	tokenRefreshMargin = time.Minute
+	tokenTimeout       = 30 * time.Second
 )
 
+// ClientForCluster returns the clients of an EKS cluster, which authenticate with tokens generated from the AWS
+// credentials. The tokens are refreshed by the transport of the clients before they expire.
 func ClientForCluster(ctx context.Context, awsCfg aws.Config, clusterName string) (*rest.Config, *kubernetes.Clientset, error) {
 	eksClient := eks.NewFromConfig(awsCfg)
 	stsClient := sts.NewFromConfig(awsCfg)
@@ -32,9 +42,10 @@ func ClientForCluster(ctx context.Context, awsCfg aws.Config, clusterName string
 	}
 
 	clusterInfo := resp.Cluster
-	bearerToken, err := generateBearerToken(ctx, stsClient, clusterName)
+	// the first token is generated right away, so that invalid AWS credentials fail here rather than on the first request
+	token, err := newToken(ctx, stsClient, clusterName)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to generate bearer token")
+		return nil, nil, err
 	}
 
 	caData, err := base64.StdEncoding.DecodeString(*clusterInfo.CertificateAuthority.Data)
@@ -43,12 +54,16 @@ func ClientForCluster(ctx context.Context, awsCfg aws.Config, clusterName string
 	}
 	// caller should parse env name from the output (.clusters[0].cluster.name)
 	cfg := rest.Config{
-		BearerToken: bearerToken,
-		Host:        *clusterInfo.Endpoint,
+		Host: *clusterInfo.Endpoint,
 		TLSClientConfig: rest.TLSClientConfig{
 			Insecure: false,
 			CAData:   caData,
 		},
+		// a bearer token set on the config would take precedence over the ones of the token source
+		WrapTransport: transport.TokenSourceWrapTransport(oauth2.ReuseTokenSource(token, &tokenSource{
+			stsClient:   stsClient,
+			clusterName: clusterName,
+		})),
 	}
 	k, err := kubernetes.NewForConfig(&cfg)
 	if err != nil {
@@ -58,6 +73,33 @@ func ClientForCluster(ctx context.Context, awsCfg aws.Config, clusterName string
 	return &cfg, k, nil
 }
 
+// GenerateToken generates a token to access an EKS cluster with, it is valid for 15 minutes
+func GenerateToken(ctx context.Context, awsCfg aws.Config, clusterName string) (*oauth2.Token, error) {
+	return newToken(ctx, sts.NewFromConfig(awsCfg), clusterName)
+}
+
+// tokenSource generates a new token whenever the cached one is about to expire
+type tokenSource struct {
+	stsClient   *sts.Client
+	clusterName string
+}
+
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+	defer cancel()
+	return newToken(ctx, s.stsClient, s.clusterName)
+}
+
+func newToken(ctx context.Context, stsClient *sts.Client, clusterName string) (*oauth2.Token, error) {
+	// the expiry is taken before generating the token, so that it is never later than the actual one
+	expiry := time.Now().Add(tokenValidity - tokenRefreshMargin)
+	bearerToken, err := generateBearerToken(ctx, stsClient, clusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate bearer token")
+	}
+	return &oauth2.Token{AccessToken: bearerToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
 func generateBearerToken(ctx context.Context, stsClient *sts.Client, clusterID string) (string, error) {
 	preSignClient := sts.NewPresignClient(stsClient)
 	preSignURLRequest, err := preSignClient.PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}, func(presignOptions *sts.PresignOptions) {
@@ -0,0 +1,169 @@
+package aws_operations
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/pkg/errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// loadBalancerDeletionTimeout bounds the wait for the load balancers of a cluster to be deleted, the AWS teardown
+// goes on after it, since the cloud provider of the cluster may never delete them, e.g. when the nodes are gone
+var loadBalancerDeletionTimeout = 5 * time.Minute
+
+const (
+	// describeTagsBatchSize is the maximum number of load balancers that the tags are described of in one call
+	describeTagsBatchSize = 20
+	// loadBalancerSecurityGroupPrefix is the name prefix of the security groups created for load balancers,
+	// e.g. k8s-elb-<name> by the cloud provider of Kubernetes
+	loadBalancerSecurityGroupPrefix = "k8s-"
+)
+
+// WaitForLoadBalancersDeleted waits for the cloud provider of a cluster to delete the load balancers of its
+// LoadBalancer services, along with their security groups, which are all tagged with the cluster. Their network
+// interfaces block deleting the VPC of the cluster until then.
+func WaitForLoadBalancersDeleted(ctx context.Context, cfg aws.Config, clusterName string) error {
+	return waitForLoadBalancersDeleted(ctx, newAWSClients(cfg), clusterName)
+}
+
+func waitForLoadBalancersDeleted(ctx context.Context, clients *awsClients, clusterName string) error {
+	childCtx, cancel := context.WithTimeout(ctx, loadBalancerDeletionTimeout)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// the load balancers of the cluster are in its VPC, so the tags of the ones in other VPCs are never described
+	vpcID, err := clusterVpcID(childCtx, clients.eks, clusterName)
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	for {
+		remaining, err = findClusterLoadBalancers(childCtx, clients, vpcID, clusterName)
+		if err != nil {
+			return err
+		}
+		securityGroups, err := findLoadBalancerSecurityGroups(childCtx, clients.ec2, clusterName)
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, securityGroups...)
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		select {
+		case <-childCtx.Done():
+			return errors.Wrapf(childCtx.Err(), "failed while waiting for the load balancers of cluster %s to be deleted, still existing: %s",
+				clusterName, strings.Join(remaining, ", "))
+		case <-ticker.C:
+		}
+	}
+}
+
+// clusterVpcID returns the VPC of a cluster, it is empty when the cluster is already gone
+func clusterVpcID(ctx context.Context, eksClient eksAPI, clusterName string) (string, error) {
+	output, err := eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe cluster %s", clusterName)
+	}
+	if output.Cluster.ResourcesVpcConfig == nil {
+		return "", nil
+	}
+	return aws.ToString(output.Cluster.ResourcesVpcConfig.VpcId), nil
+}
+
+// findClusterLoadBalancers finds both the classic load balancers and the network load balancers tagged with a cluster.
+// Only the load balancers in vpcID are considered when it is not empty, the VPC may be shared with other clusters.
+func findClusterLoadBalancers(ctx context.Context, clients *awsClients, vpcID, clusterName string) ([]string, error) {
+	clusterTag := fmt.Sprintf(kubernetesTagFormat, clusterName)
+	var found []string
+
+	var names []string
+	classicPaginator := elb.NewDescribeLoadBalancersPaginator(clients.elb, &elb.DescribeLoadBalancersInput{})
+	for classicPaginator.HasMorePages() {
+		page, err := classicPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list classic load balancers")
+		}
+		for _, lb := range page.LoadBalancerDescriptions {
+			if vpcID == "" || aws.ToString(lb.VPCId) == vpcID {
+				names = append(names, aws.ToString(lb.LoadBalancerName))
+			}
+		}
+	}
+	for batch := range slices.Chunk(names, describeTagsBatchSize) {
+		tagsOutput, err := clients.elb.DescribeTags(ctx, &elb.DescribeTagsInput{LoadBalancerNames: batch})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the tags of classic load balancers")
+		}
+		for _, description := range tagsOutput.TagDescriptions {
+			for _, tag := range description.Tags {
+				if aws.ToString(tag.Key) == clusterTag {
+					found = append(found, aws.ToString(description.LoadBalancerName))
+				}
+			}
+		}
+	}
+
+	var arns []string
+	paginator := elbv2.NewDescribeLoadBalancersPaginator(clients.elbv2, &elbv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list load balancers")
+		}
+		for _, lb := range page.LoadBalancers {
+			if vpcID == "" || aws.ToString(lb.VpcId) == vpcID {
+				arns = append(arns, aws.ToString(lb.LoadBalancerArn))
+			}
+		}
+	}
+	for batch := range slices.Chunk(arns, describeTagsBatchSize) {
+		tagsOutput, err := clients.elbv2.DescribeTags(ctx, &elbv2.DescribeTagsInput{ResourceArns: batch})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe the tags of load balancers")
+		}
+		for _, description := range tagsOutput.TagDescriptions {
+			for _, tag := range description.Tags {
+				if aws.ToString(tag.Key) == clusterTag {
+					found = append(found, aws.ToString(description.ResourceArn))
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// findLoadBalancerSecurityGroups finds the security groups of the load balancers of a cluster, the security groups
+// created along with the cluster are tagged with the cluster as well, but they are not named with the prefix
+func findLoadBalancerSecurityGroups(ctx context.Context, ec2Client ec2API, clusterName string) ([]string, error) {
+	sgOutput, err := ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("tag-key"), Values: []string{fmt.Sprintf(kubernetesTagFormat, clusterName)}},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the security groups of cluster %s", clusterName)
+	}
+
+	var found []string
+	for _, sg := range sgOutput.SecurityGroups {
+		if strings.HasPrefix(aws.ToString(sg.GroupName), loadBalancerSecurityGroupPrefix) {
+			found = append(found, aws.ToString(sg.GroupId))
+		}
+	}
+	return found, nil
+}
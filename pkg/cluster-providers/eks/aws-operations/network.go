@@ -7,6 +7,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	"net/netip"
+	"slices"
+	"time"
 )
 
 const (
@@ -15,7 +18,7 @@ const (
 	defaultSubnetCIDR2 = "10.163.2.0/24"
 )
 
-func getAvailabilityZones(ctx context.Context, ec2Client *ec2.Client, region string) ([]string, error) {
+func getAvailabilityZones(ctx context.Context, ec2Client ec2API, region string) ([]string, error) {
 	availabilityZonesOutput, err := ec2Client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to describe availability zones")
@@ -32,15 +35,61 @@ func getAvailabilityZones(ctx context.Context, ec2Client *ec2.Client, region str
 	return subnetAvZones, nil
 }
 
-func createVPC(ctx context.Context, ec2Client *ec2.Client, subnetAvZones []string) (string, []string, error) {
+// validateExistingSubnets checks that the subnets of an existing VPC can host a cluster and returns their availability
+// zones. EKS requires the subnets to be in at least two availability zones, and the subnets of IPv6 clusters to be dual-stack.
+func validateExistingSubnets(ctx context.Context, ec2Client ec2API, vpcID string, subnetIDs []string, ipv6 bool) ([]string, error) {
+	subnetsOutput, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: subnetIDs})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe subnets")
+	}
+
+	var subnetAvZones []string
+	for _, subnet := range subnetsOutput.Subnets {
+		subnetID := aws.ToString(subnet.SubnetId)
+		if aws.ToString(subnet.VpcId) != vpcID {
+			return nil, errors.Errorf("subnet %s is in VPC %s", subnetID, aws.ToString(subnet.VpcId))
+		}
+		if ipv6 && len(subnet.Ipv6CidrBlockAssociationSet) == 0 {
+			return nil, errors.Errorf("subnet %s has no IPv6 CIDR block, which the subnets of IPv6 clusters require", subnetID)
+		}
+		if !slices.Contains(subnetAvZones, aws.ToString(subnet.AvailabilityZone)) {
+			subnetAvZones = append(subnetAvZones, aws.ToString(subnet.AvailabilityZone))
+		}
+	}
+	if len(subnetAvZones) < 2 {
+		return nil, errors.Errorf("the subnets are in %d availability zones, at least 2 are required", len(subnetAvZones))
+	}
+	return subnetAvZones, nil
+}
+
+// createVPC creates a VPC with a public subnet in each of the availability zones. With ipv6, the VPC and the subnets
+// are dual-stack, each subnet gets a /64 block of the /56 block that Amazon provides to the VPC.
+func createVPC(ctx context.Context, ec2Client ec2API, journal *Journal, clusterName string, subnetAvZones []string, ipv6 bool) (string, []string, error) {
 	vpcOutput, err := ec2Client.CreateVpc(ctx, &ec2.CreateVpcInput{
-		CidrBlock: aws.String(defaultVPCCIDR),
+		CidrBlock:                   aws.String(defaultVPCCIDR),
+		AmazonProvidedIpv6CidrBlock: aws.Bool(ipv6),
+		TagSpecifications:           clusterTagSpecifications(ec2Types.ResourceTypeVpc, clusterName),
 	})
 	if err != nil {
 		return "", nil, errors.Wrap(err, "failed to create VPC")
 	}
 
 	vpcID := *vpcOutput.Vpc.VpcId
+	if err := journal.record(ResourceVPC, vpcID, ""); err != nil {
+		return "", nil, err
+	}
+	var subnetIPv6CIDRs [2]string
+	if ipv6 {
+		vpcIPv6CIDR, err := waitForVPCIPv6CIDR(ctx, ec2Client, vpcID)
+		if err != nil {
+			return "", nil, err
+		}
+		for i := range subnetIPv6CIDRs {
+			if subnetIPv6CIDRs[i], err = ipv6SubnetCIDR(vpcIPv6CIDR, i+1); err != nil {
+				return "", nil, err
+			}
+		}
+	}
 	_, err = ec2Client.ModifyVpcAttribute(context.TODO(), &ec2.ModifyVpcAttributeInput{
 		VpcId: aws.String(vpcID),
 		EnableDnsSupport: &ec2Types.AttributeBooleanValue{
@@ -60,10 +109,15 @@ func createVPC(ctx context.Context, ec2Client *ec2.Client, subnetAvZones []strin
 		return "", nil, errors.Wrapf(err, "failed to enable DNS support for VPC %s", vpcID)
 	}
 
-	igwOutput, err := ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{})
+	igwOutput, err := ec2Client.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{
+		TagSpecifications: clusterTagSpecifications(ec2Types.ResourceTypeInternetGateway, clusterName),
+	})
 	if err != nil {
 		return "", nil, errors.Wrap(err, "unable to create Internet Gateway")
 	}
+	if err := journal.record(ResourceInternetGateway, *igwOutput.InternetGateway.InternetGatewayId, ""); err != nil {
+		return "", nil, err
+	}
 	_, err = ec2Client.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
 		InternetGatewayId: igwOutput.InternetGateway.InternetGatewayId,
 		VpcId:             vpcOutput.Vpc.VpcId,
@@ -71,12 +125,19 @@ func createVPC(ctx context.Context, ec2Client *ec2.Client, subnetAvZones []strin
 	if err != nil {
 		return "", nil, errors.Wrapf(err, "unable to add Internet Gateway %s within the VPC %s", *igwOutput.InternetGateway.InternetGatewayId, vpcID)
 	}
+	if err := journal.record(ResourceGatewayAttachment, *igwOutput.InternetGateway.InternetGatewayId, vpcID); err != nil {
+		return "", nil, err
+	}
 	rtOutput, err := ec2Client.CreateRouteTable(ctx, &ec2.CreateRouteTableInput{
-		VpcId: vpcOutput.Vpc.VpcId,
+		VpcId:             vpcOutput.Vpc.VpcId,
+		TagSpecifications: clusterTagSpecifications(ec2Types.ResourceTypeRouteTable, clusterName),
 	})
 	if err != nil {
 		return "", nil, errors.Wrapf(err, "failed to create Route Table")
 	}
+	if err := journal.record(ResourceRouteTable, *rtOutput.RouteTable.RouteTableId, vpcID); err != nil {
+		return "", nil, err
+	}
 	_, err = ec2Client.CreateRoute(ctx, &ec2.CreateRouteInput{
 		RouteTableId:         rtOutput.RouteTable.RouteTableId,
 		GatewayId:            igwOutput.InternetGateway.InternetGatewayId,
@@ -86,12 +147,23 @@ func createVPC(ctx context.Context, ec2Client *ec2.Client, subnetAvZones []strin
 		return "", nil, errors.Wrapf(err, "failed to create default egress route for Route Table %s",
 			*rtOutput.RouteTable.RouteTableId)
 	}
+	if ipv6 {
+		_, err = ec2Client.CreateRoute(ctx, &ec2.CreateRouteInput{
+			RouteTableId:             rtOutput.RouteTable.RouteTableId,
+			GatewayId:                igwOutput.InternetGateway.InternetGatewayId,
+			DestinationIpv6CidrBlock: aws.String("::/0"),
+		})
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to create default IPv6 egress route for Route Table %s",
+				*rtOutput.RouteTable.RouteTableId)
+		}
+	}
 
-	subnetId1, err := createSubnet(ctx, ec2Client, vpcID, defaultSubnetCIDR1, subnetAvZones[0], *rtOutput.RouteTable.RouteTableId)
+	subnetId1, err := createSubnet(ctx, ec2Client, journal, clusterName, vpcID, defaultSubnetCIDR1, subnetIPv6CIDRs[0], subnetAvZones[0], *rtOutput.RouteTable.RouteTableId)
 	if err != nil {
 		return "", nil, errors.Wrapf(err, "failed to create subnet within the VPC %s", vpcID)
 	}
-	subnetId2, err := createSubnet(ctx, ec2Client, vpcID, defaultSubnetCIDR2, subnetAvZones[1], *rtOutput.RouteTable.RouteTableId)
+	subnetId2, err := createSubnet(ctx, ec2Client, journal, clusterName, vpcID, defaultSubnetCIDR2, subnetIPv6CIDRs[1], subnetAvZones[1], *rtOutput.RouteTable.RouteTableId)
 	if err != nil {
 		return "", nil, errors.Wrapf(err, "failed to create subnet within the VPC %s", vpcID)
 	}
@@ -100,17 +172,26 @@ func createVPC(ctx context.Context, ec2Client *ec2.Client, subnetAvZones []strin
 	return vpcID, subnetIDs, nil
 }
 
-func createSubnet(ctx context.Context, ec2Client *ec2.Client, vpcID, cidrBlock, availabilityZone, routeTableId string) (string, error) {
-	subnet1Output, err := ec2Client.CreateSubnet(ctx, &ec2.CreateSubnetInput{
-		VpcId:            aws.String(vpcID),
-		CidrBlock:        aws.String(cidrBlock),
-		AvailabilityZone: aws.String(availabilityZone),
-	})
+// createSubnet creates a public subnet, it is dual-stack when ipv6CidrBlock is not empty
+func createSubnet(ctx context.Context, ec2Client ec2API, journal *Journal, clusterName, vpcID, cidrBlock, ipv6CidrBlock, availabilityZone, routeTableId string) (string, error) {
+	input := &ec2.CreateSubnetInput{
+		VpcId:             aws.String(vpcID),
+		CidrBlock:         aws.String(cidrBlock),
+		AvailabilityZone:  aws.String(availabilityZone),
+		TagSpecifications: clusterTagSpecifications(ec2Types.ResourceTypeSubnet, clusterName),
+	}
+	if ipv6CidrBlock != "" {
+		input.Ipv6CidrBlock = aws.String(ipv6CidrBlock)
+	}
+	subnet1Output, err := ec2Client.CreateSubnet(ctx, input)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to create subnet within the VPC %s", vpcID)
 	}
 
 	subnetId := subnet1Output.Subnet.SubnetId
+	if err := journal.record(ResourceSubnet, *subnetId, vpcID); err != nil {
+		return "", err
+	}
 	_, err = ec2Client.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
 		SubnetId:            subnetId,
 		MapPublicIpOnLaunch: &ec2Types.AttributeBooleanValue{Value: aws.Bool(true)},
@@ -118,62 +199,98 @@ func createSubnet(ctx context.Context, ec2Client *ec2.Client, vpcID, cidrBlock,
 	if err != nil {
 		return "", errors.Wrapf(err, "unable to modify subnet %s to enable public IP assignment", *subnetId)
 	}
+	if ipv6CidrBlock != "" {
+		_, err = ec2Client.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+			SubnetId:                    subnetId,
+			AssignIpv6AddressOnCreation: &ec2Types.AttributeBooleanValue{Value: aws.Bool(true)},
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to modify subnet %s to enable IPv6 address assignment", *subnetId)
+		}
+	}
 
 	if routeTableId != "" {
-		_, err = ec2Client.AssociateRouteTable(ctx, &ec2.AssociateRouteTableInput{
+		assocOutput, err := ec2Client.AssociateRouteTable(ctx, &ec2.AssociateRouteTableInput{
 			RouteTableId: aws.String(routeTableId),
 			SubnetId:     subnetId,
 		})
 		if err != nil {
 			return "", errors.Wrapf(err, "failed to associate Route Table %s with subnet %s", routeTableId, *subnetId)
 		}
+		if err := journal.record(ResourceRouteTableAssociation, aws.ToString(assocOutput.AssociationId), routeTableId); err != nil {
+			return "", err
+		}
 	}
 	return *subnetId, nil
 }
 
-func createControlPlaneSecurityGroup(ctx context.Context, ec2Client *ec2.Client, vpcId, clusterName string) (string, error) {
+// waitForVPCIPv6CIDR waits for the IPv6 CIDR block provided by Amazon to be associated with the VPC and returns it
+func waitForVPCIPv6CIDR(ctx context.Context, ec2Client ec2API, vpcID string) (string, error) {
+	childCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-childCtx.Done():
+			return "", errors.Wrapf(childCtx.Err(), "failed while waiting for the IPv6 CIDR block of VPC %s", vpcID)
+		case <-ticker.C:
+			vpcsOutput, err := ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to describe VPC %s", vpcID)
+			}
+			for _, vpc := range vpcsOutput.Vpcs {
+				for _, assoc := range vpc.Ipv6CidrBlockAssociationSet {
+					if assoc.Ipv6CidrBlockState != nil && assoc.Ipv6CidrBlockState.State == ec2Types.VpcCidrBlockStateCodeAssociated {
+						return aws.ToString(assoc.Ipv6CidrBlock), nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// ipv6SubnetCIDR returns the /64 block numbered index within the /56 block of a VPC
+func ipv6SubnetCIDR(vpcCIDR string, index int) (string, error) {
+	prefix, err := netip.ParsePrefix(vpcCIDR)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid IPv6 CIDR block %s", vpcCIDR)
+	}
+	if !prefix.Addr().Is6() || prefix.Bits() > 56 || index < 0 || index > 255 {
+		return "", errors.Errorf("IPv6 CIDR block %s has no /64 block numbered %d", vpcCIDR, index)
+	}
+
+	addr := prefix.Masked().Addr().As16()
+	addr[7] = byte(index)
+	return netip.PrefixFrom(netip.AddrFrom16(addr), 64).String(), nil
+}
+
+func createControlPlaneSecurityGroup(ctx context.Context, ec2Client ec2API, journal *Journal, vpcId, clusterName string) (string, error) {
 	sg1Output, err := ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
-		GroupName:   aws.String(fmt.Sprintf("%s-cp", clusterName)),
-		Description: aws.String("Allow communication between the control plane and worker nodes"),
-		VpcId:       aws.String(vpcId),
-		TagSpecifications: []ec2Types.TagSpecification{
-			{
-				ResourceType: ec2Types.ResourceTypeSecurityGroup,
-				Tags: []ec2Types.Tag{
-					{
-						Key:   aws.String(fmt.Sprintf(kubernetesTagFormat, clusterName)),
-						Value: aws.String("owned"),
-					},
-				},
-			},
-		},
+		GroupName:         aws.String(fmt.Sprintf("%s-cp", clusterName)),
+		Description:       aws.String("Allow communication between the control plane and worker nodes"),
+		VpcId:             aws.String(vpcId),
+		TagSpecifications: clusterTagSpecifications(ec2Types.ResourceTypeSecurityGroup, clusterName),
 	})
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create security group")
 	}
-	return *sg1Output.GroupId, nil
+	return *sg1Output.GroupId, journal.record(ResourceSecurityGroup, *sg1Output.GroupId, vpcId)
 }
 
-func createNodeSecurityGroup(ctx context.Context, ec2Client *ec2.Client, vpcId, clusterName string, cpDefaultSecurityGroupIds []string) (string, error) {
+func createNodeSecurityGroup(ctx context.Context, ec2Client ec2API, journal *Journal, vpcId, clusterName string, cpDefaultSecurityGroupIds []string) (string, error) {
 	sgOutput, err := ec2Client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
-		GroupName:   aws.String(fmt.Sprintf("%s-shared-by-all-nodes", clusterName)),
-		Description: aws.String("Allow communication between all nodes in the cluster"),
-		VpcId:       aws.String(vpcId),
-		TagSpecifications: []ec2Types.TagSpecification{
-			{
-				ResourceType: ec2Types.ResourceTypeSecurityGroup,
-				Tags: []ec2Types.Tag{
-					{
-						Key:   aws.String(fmt.Sprintf(kubernetesTagFormat, clusterName)),
-						Value: aws.String("owned"),
-					},
-				},
-			},
-		},
+		GroupName:         aws.String(fmt.Sprintf("%s-shared-by-all-nodes", clusterName)),
+		Description:       aws.String("Allow communication between all nodes in the cluster"),
+		VpcId:             aws.String(vpcId),
+		TagSpecifications: clusterTagSpecifications(ec2Types.ResourceTypeSecurityGroup, clusterName),
 	})
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create node security group")
 	}
+	if err := journal.record(ResourceSecurityGroup, *sgOutput.GroupId, vpcId); err != nil {
+		return "", err
+	}
 
 	for _, sgId := range cpDefaultSecurityGroupIds {
 		_, err = ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
@@ -193,6 +310,9 @@ func createNodeSecurityGroup(ctx context.Context, ec2Client *ec2.Client, vpcId,
 			return "", errors.Wrapf(err, "failed to authorize inbound traffic from control plane security group %s to node security group %s",
 				sgId, *sgOutput.GroupId)
 		}
+		if err := journal.record(ResourceSecurityGroupIngress, *sgOutput.GroupId, sgId); err != nil {
+			return "", err
+		}
 
 		_, err = ec2Client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
 			GroupId: aws.String(sgId),
@@ -211,12 +331,100 @@ func createNodeSecurityGroup(ctx context.Context, ec2Client *ec2.Client, vpcId,
 			return "", errors.Wrapf(err, "failed to authorize inbound traffic from node security group %s to control plane security group %s",
 				*sgOutput.GroupId, sgId)
 		}
+		// the rule has to be revoked before deleting the node security group, which it refers to
+		if err := journal.record(ResourceSecurityGroupIngress, sgId, *sgOutput.GroupId); err != nil {
+			return "", err
+		}
 	}
 
 	return *sgOutput.GroupId, nil
 }
 
-func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
+// clusterTagSpecifications tags a resource to create as owned by a cluster, so that the resource is found by the
+// tag when cleaning up the cluster, even if the creation of the cluster was interrupted
+func clusterTagSpecifications(resourceType ec2Types.ResourceType, clusterName string) []ec2Types.TagSpecification {
+	return []ec2Types.TagSpecification{
+		{
+			ResourceType: resourceType,
+			Tags: []ec2Types.Tag{
+				{
+					Key:   aws.String(fmt.Sprintf(kubernetesTagFormat, clusterName)),
+					Value: aws.String(ownedTagValue),
+				},
+			},
+		},
+	}
+}
+
+// clusterTagFilter matches the resources owned by a cluster
+func clusterTagFilter(clusterName string) ec2Types.Filter {
+	return ec2Types.Filter{
+		Name:   aws.String("tag:" + fmt.Sprintf(kubernetesTagFormat, clusterName)),
+		Values: []string{ownedTagValue},
+	}
+}
+
+// findClusterVPCs finds the VPCs owned by a cluster
+func findClusterVPCs(ctx context.Context, ec2Client ec2API, clusterName string) ([]string, error) {
+	vpcsOutput, err := ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: []ec2Types.Filter{clusterTagFilter(clusterName)},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the VPCs of cluster %s", clusterName)
+	}
+
+	var vpcIDs []string
+	for _, vpc := range vpcsOutput.Vpcs {
+		vpcIDs = append(vpcIDs, aws.ToString(vpc.VpcId))
+	}
+	return vpcIDs, nil
+}
+
+// deleteClusterInternetGateways deletes the internet gateways owned by a cluster that are not attached to any VPC,
+// the attached ones are deleted along with their VPCs
+func deleteClusterInternetGateways(ctx context.Context, ec2Client ec2API, clusterName string) error {
+	igwsOutput, err := ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []ec2Types.Filter{clusterTagFilter(clusterName)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the internet gateways of cluster %s", clusterName)
+	}
+
+	var errs cleanupErrors
+	for _, igw := range igwsOutput.InternetGateways {
+		if len(igw.Attachments) > 0 {
+			continue
+		}
+		_, err := ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
+			InternetGatewayId: igw.InternetGatewayId,
+		})
+		errs.add(errors.Wrapf(err, "failed to delete internet gateway %s", aws.ToString(igw.InternetGatewayId)))
+	}
+	return errs.err()
+}
+
+// deleteVPC deletes a VPC with everything in it. It carries on when deleting a resource in the VPC fails,
+// and returns all the failures. The VPC itself is only deleted when everything in it is deleted.
+func deleteVPC(ctx context.Context, ec2Client ec2API, vpcID string) error {
+	var errs cleanupErrors
+	errs.add(deleteVPCRouteTables(ctx, ec2Client, vpcID))
+	errs.add(deleteVPCSubnets(ctx, ec2Client, vpcID))
+	errs.add(deleteVPCInternetGateways(ctx, ec2Client, vpcID))
+	errs.add(deleteVPCSecurityGroups(ctx, ec2Client, vpcID))
+	if err := errs.err(); err != nil {
+		return errors.Wrapf(err, "failed to delete the resources in VPC %s", vpcID)
+	}
+
+	_, err := ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{
+		VpcId: aws.String(vpcID),
+	})
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete VPC %s", vpcID)
+	}
+	return nil
+}
+
+func deleteVPCRouteTables(ctx context.Context, ec2Client ec2API, vpcID string) error {
 	routeTablesOutput, err := ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
 		Filters: []ec2Types.Filter{
 			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
@@ -226,6 +434,7 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 		return errors.Wrapf(err, "failed to list route tables in VPC %s", vpcID)
 	}
 
+	var errs cleanupErrors
 	for _, rt := range routeTablesOutput.RouteTables {
 		isMain := false
 		for _, assoc := range rt.Associations {
@@ -243,20 +452,19 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 				_, err := ec2Client.DisassociateRouteTable(ctx, &ec2.DisassociateRouteTableInput{
 					AssociationId: assoc.RouteTableAssociationId,
 				})
-				if err != nil {
-					return errors.Wrapf(err, "failed to disassociate route table association %s for route table %s", *assoc.RouteTableAssociationId, *rt.RouteTableId)
-				}
+				errs.add(errors.Wrapf(err, "failed to disassociate route table association %s for route table %s", *assoc.RouteTableAssociationId, *rt.RouteTableId))
 			}
 		}
 
 		_, err := ec2Client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{
 			RouteTableId: rt.RouteTableId,
 		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to delete route table %s", *rt.RouteTableId)
-		}
+		errs.add(errors.Wrapf(err, "failed to delete route table %s", *rt.RouteTableId))
 	}
+	return errs.err()
+}
 
+func deleteVPCSubnets(ctx context.Context, ec2Client ec2API, vpcID string) error {
 	subnetsOutput, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
 		Filters: []ec2Types.Filter{
 			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
@@ -266,15 +474,17 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 		return errors.Wrapf(err, "failed to describe subnets in VPC %s", vpcID)
 	}
 
+	var errs cleanupErrors
 	for _, subnet := range subnetsOutput.Subnets {
 		_, err := ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{
 			SubnetId: subnet.SubnetId,
 		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to delete subnet %s", *subnet.SubnetId)
-		}
+		errs.add(errors.Wrapf(err, "failed to delete subnet %s", *subnet.SubnetId))
 	}
+	return errs.err()
+}
 
+func deleteVPCInternetGateways(ctx context.Context, ec2Client ec2API, vpcID string) error {
 	igwsOutput, err := ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
 		Filters: []ec2Types.Filter{
 			{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}},
@@ -284,23 +494,26 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 		return errors.Wrapf(err, "failed to describe internet gateways in VPC %s", vpcID)
 	}
 
+	var errs cleanupErrors
 	for _, igw := range igwsOutput.InternetGateways {
 		_, err := ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
 			InternetGatewayId: igw.InternetGatewayId,
 			VpcId:             aws.String(vpcID),
 		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to detach internet gateway %s", *igw.InternetGatewayId)
+		if err != nil && !isNotFound(err) {
+			errs.add(errors.Wrapf(err, "failed to detach internet gateway %s", *igw.InternetGatewayId))
+			continue
 		}
 
 		_, err = ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
 			InternetGatewayId: igw.InternetGatewayId,
 		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to delete internet gateway %s", *igw.InternetGatewayId)
-		}
+		errs.add(errors.Wrapf(err, "failed to delete internet gateway %s", *igw.InternetGatewayId))
 	}
+	return errs.err()
+}
 
+func deleteVPCSecurityGroups(ctx context.Context, ec2Client ec2API, vpcID string) error {
 	sgOutput, err := ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
 		Filters: []ec2Types.Filter{
 			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
@@ -309,8 +522,26 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to describe security groups in VPC %s", vpcID)
 	}
+	return deleteSecurityGroups(ctx, ec2Client, sgOutput.SecurityGroups)
+}
 
-	for _, sg := range sgOutput.SecurityGroups {
+// deleteClusterSecurityGroups deletes the security groups owned by a cluster that are left in VPCs that are not
+// deleted along with the cluster, e.g. an existing VPC that the cluster was created in
+func deleteClusterSecurityGroups(ctx context.Context, ec2Client ec2API, clusterName string) error {
+	sgOutput, err := ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2Types.Filter{clusterTagFilter(clusterName)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the security groups of cluster %s", clusterName)
+	}
+	return deleteSecurityGroups(ctx, ec2Client, sgOutput.SecurityGroups)
+}
+
+// deleteSecurityGroups deletes security groups except the default ones of VPCs. The rules are revoked first,
+// since the security groups can't be deleted while the rules of others refer to them.
+func deleteSecurityGroups(ctx context.Context, ec2Client ec2API, securityGroups []ec2Types.SecurityGroup) error {
+	var errs cleanupErrors
+	for _, sg := range securityGroups {
 		if sg.GroupName != nil && *sg.GroupName == "default" {
 			continue
 		}
@@ -320,10 +551,8 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 				GroupId:       sg.GroupId,
 				IpPermissions: []ec2Types.IpPermission{ingress},
 			})
-			if err != nil {
-				return errors.Wrapf(err, "failed to revoke a %s ingress rule on security group %s",
-					aws.ToString(ingress.IpProtocol), aws.ToString(sg.GroupId))
-			}
+			errs.add(errors.Wrapf(err, "failed to revoke a %s ingress rule on security group %s",
+				aws.ToString(ingress.IpProtocol), aws.ToString(sg.GroupId)))
 		}
 
 		for _, egress := range sg.IpPermissionsEgress {
@@ -331,14 +560,12 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 				GroupId:       sg.GroupId,
 				IpPermissions: []ec2Types.IpPermission{egress},
 			})
-			if err != nil {
-				return errors.Wrapf(err, "failed to revoke a %s egress rule on security group %s",
-					aws.ToString(egress.IpProtocol), aws.ToString(sg.GroupId))
-			}
+			errs.add(errors.Wrapf(err, "failed to revoke a %s egress rule on security group %s",
+				aws.ToString(egress.IpProtocol), aws.ToString(sg.GroupId)))
 		}
 	}
 
-	for _, sg := range sgOutput.SecurityGroups {
+	for _, sg := range securityGroups {
 		if sg.GroupName != nil && *sg.GroupName == "default" {
 			continue
 		}
@@ -346,17 +573,7 @@ func deleteVPC(ctx context.Context, ec2Client *ec2.Client, vpcID string) error {
 		_, err := ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
 			GroupId: sg.GroupId,
 		})
-		if err != nil {
-			return errors.Wrapf(err, "failed to delete security group %s", *sg.GroupId)
-		}
+		errs.add(errors.Wrapf(err, "failed to delete security group %s", *sg.GroupId))
 	}
-
-	_, err = ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{
-		VpcId: aws.String(vpcID),
-	})
-	if err != nil {
-		return errors.Wrapf(err, "failed to delete VPC %s", vpcID)
-	}
-
-	return nil
+	return errs.err()
 }
@@ -3,13 +3,12 @@ package eks
 import (
 	"context"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/blang/semver/v4"
 	"github.com/google/uuid"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
 	"github.com/kumahq/kuma-smoke/pkg/cluster-providers/eks/aws-operations"
-	"github.com/pkg/errors"
-	"strings"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
 )
 
 // Builder generates clusters.Cluster objects backed by GKE given
@@ -20,10 +19,17 @@ type Builder struct {
 	addons          clusters.Addons
 	clusterVersion  *semver.Version
 	nodeMachineType string
+	nodeCount       int
+	region          string
+	labels          map[string]string
+	ipFamily        clusters.IPFamily
+	vpcID           string
+	subnetIDs       []string
 }
 
 const (
 	defaultNodeMachineType   = "c5.4xlarge"
+	defaultNodeCount         = 1
 	defaultKubernetesVersion = "1.31.1"
 )
 
@@ -33,6 +39,7 @@ func NewBuilder() *Builder {
 	return &Builder{
 		Name:            fmt.Sprintf("t-%s", uuid.NewString()),
 		nodeMachineType: defaultNodeMachineType,
+		nodeCount:       defaultNodeCount,
 		addons:          make(clusters.Addons),
 		clusterVersion:  &k8sVer,
 	}
@@ -56,33 +63,83 @@ func (b *Builder) WithNodeMachineType(machineType string) *Builder {
 	return b
 }
 
+func (b *Builder) WithNodeCount(count int) *Builder {
+	b.nodeCount = count
+	return b
+}
+
+// WithRegion configures the region to create the cluster in, instead of the one set by the AWS_REGION env var.
+func (b *Builder) WithRegion(region string) *Builder {
+	b.region = region
+	return b
+}
+
+// WithIPFamily configures the IP family of the cluster networking, EKS supports IPv4 and IPv6 but not dual-stack.
+func (b *Builder) WithIPFamily(ipFamily clusters.IPFamily) *Builder {
+	b.ipFamily = ipFamily
+	return b
+}
+
+// WithExistingNetwork configures an existing VPC and its subnets to create the cluster in, instead of creating
+// a VPC for the cluster. Deleting the cluster leaves them alone.
+func (b *Builder) WithExistingNetwork(vpcID string, subnetIDs []string) *Builder {
+	b.vpcID = vpcID
+	b.subnetIDs = subnetIDs
+	return b
+}
+
+// WithLabels adds tags that the created cluster is going to be tagged with.
+func (b *Builder) WithLabels(labels map[string]string) *Builder {
+	if b.labels == nil {
+		b.labels = map[string]string{}
+	}
+	for k, v := range labels {
+		b.labels[k] = v
+	}
+	return b
+}
+
 // Build creates and configures clients for an EKS-based Kubernetes clusters.Cluster.
 func (b *Builder) Build(ctx context.Context) (clusters.Cluster, error) {
-	err := guardOnEnv()
+	cfg, err := loadAWSConfig(ctx, b.region)
 	if err != nil {
 		return nil, err
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to load AWS SDK config")
+	k8sMinorVersion := minorVersion(b.clusterVersion)
+	if err := aws_operations.ValidateKubernetesVersion(ctx, cfg, k8sMinorVersion); err != nil {
+		return nil, err
 	}
 
-	err = aws_operations.CreateEKSClusterAll(ctx, cfg, b.Name, minorVersion(b.clusterVersion), b.nodeMachineType)
+	resources, err := aws_operations.CreateEKSClusterAll(ctx, cfg, aws_operations.ClusterSpec{
+		Name:                   b.Name,
+		KubernetesMinorVersion: k8sMinorVersion,
+		NodeMachineType:        b.nodeMachineType,
+		NodeCount:              b.nodeCount,
+		IPFamily:               types.IpFamily(b.ipFamily),
+		VpcID:                  b.vpcID,
+		SubnetIDs:              b.subnetIDs,
+		Tags:                   b.labels,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// EKS limits the maximum allowed validity of an STS token to 15min (900s)
-	return InitFromExisting(ctx, cfg, b.Name)
-
+	// the journal of the creation is already completed, so the cluster is deleted here, otherwise it would leak
+	// when the caller did not record the environment
+	cluster, err := InitFromExisting(ctx, cfg, b.Name)
+	if err != nil {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), utils.CleanupTimeout)
+		defer cancel()
+		if cleanupErr := aws_operations.DeleteEKSClusterAll(cleanupCtx, cfg, b.Name); cleanupErr != nil {
+			return nil, fmt.Errorf("%w, and deleting the created cluster failed: %v", err, cleanupErr)
+		}
+		return nil, err
+	}
+	cluster.resources = resources
+	return cluster, nil
 }
 
 func minorVersion(v *semver.Version) string {
-	fullStr := v.String()
-	lastIndexOfDot := strings.LastIndex(fullStr, ".")
-	if lastIndexOfDot == -1 {
-		lastIndexOfDot = 1
-	}
-	return fullStr[:lastIndexOfDot]
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
 }
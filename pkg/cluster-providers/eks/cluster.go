@@ -15,6 +15,8 @@ import (
 	"sync"
 
 	"github.com/blang/semver/v4"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -34,6 +36,10 @@ type Cluster struct {
 	addons   clusters.Addons
 	l        *sync.RWMutex
 	ipFamily clusters.IPFamily
+	awsCfg   aws.Config
+
+	// resources are only known when the cluster is created by this process
+	resources *aws_operations.ClusterResources
 }
 
 // InitFromExisting provides a new clusters.Cluster backed by an existing EKS cluster,
@@ -43,26 +49,42 @@ func InitFromExisting(ctx context.Context, cfg aws.Config, name string) (*Cluste
 	if err != nil {
 		return nil, err_pkg.Wrapf(err, "failed to get kube client for cluster %s", name)
 	}
+	ipFamily, err := aws_operations.ClusterIPFamily(ctx, cfg, name)
+	if err != nil {
+		return nil, err
+	}
 	return &Cluster{
-		name:   name,
-		client: kubeCfg,
-		cfg:    restCfg,
-		addons: make(clusters.Addons),
-		l:      &sync.RWMutex{},
+		name:     name,
+		client:   kubeCfg,
+		cfg:      restCfg,
+		awsCfg:   cfg,
+		addons:   make(clusters.Addons),
+		l:        &sync.RWMutex{},
+		ipFamily: clusters.IPFamily(ipFamily),
 	}, nil
 }
 
-func guardOnEnv() error {
+// loadAWSConfig loads the AWS SDK config from the environment variables, region overrides the region set by them
+func loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
 	if os.Getenv(envAccessKeyId) == "" {
-		return errors.New(envAccessKeyId + " is not set")
+		return aws.Config{}, errors.New(envAccessKeyId + " is not set")
 	}
 	if os.Getenv(envAccessKey) == "" {
-		return errors.New(envAccessKey + " is not set")
+		return aws.Config{}, errors.New(envAccessKey + " is not set")
 	}
-	if os.Getenv(envRegion) == "" {
-		return errors.New(envRegion + " is not set")
+	if os.Getenv(envRegion) == "" && region == "" {
+		return aws.Config{}, errors.New(envRegion + " is not set")
 	}
-	return nil
+
+	var loadOpts []func(*config.LoadOptions) error
+	if region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, err_pkg.Wrap(err, "failed to load AWS SDK config")
+	}
+	return cfg, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -89,17 +111,45 @@ func (c *Cluster) Cleanup(ctx context.Context) error {
 	c.l.Lock()
 	defer c.l.Unlock()
 
-	err := guardOnEnv()
+	// the teardown goes on when deleting the load balancers fails, deleting the VPC fails then and tells what is left
+	lbErr := c.deleteLoadBalancers(ctx)
+	return errors.Join(lbErr, cleanupCluster(ctx, c.awsCfg, c.Name()))
+}
+
+// deleteLoadBalancers deletes the LoadBalancer services of the cluster and waits for their load balancers to be
+// deleted. It has to be done before deleting the cluster, since the load balancers are deleted by the cloud provider
+// running with the cluster, and their network interfaces block deleting the VPC.
+func (c *Cluster) deleteLoadBalancers(ctx context.Context) error {
+	services, err := c.client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return err
+		return err_pkg.Wrap(err, "failed to list services")
+	}
+	for _, svc := range services.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		err := c.client.CoreV1().Services(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err_pkg.Wrapf(err, "failed to delete LoadBalancer service %s/%s", svc.Namespace, svc.Name)
+		}
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+	return aws_operations.WaitForLoadBalancersDeleted(ctx, c.awsCfg, c.Name())
+}
+
+func cleanupCluster(ctx context.Context, cfg aws.Config, name string) error {
+	// a journal is left when the creation of the cluster was interrupted, it knows all the resources created so far
+	journal, err := aws_operations.LoadJournal(name)
 	if err != nil {
-		return err_pkg.Wrap(err, "failed to load AWS SDK config")
+		return err
 	}
-
-	return aws_operations.DeleteEKSClusterAll(ctx, cfg, c.Name())
+	if journal != nil {
+		if err := journal.Rollback(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	// anything that the journal does not know, e.g. what an earlier failed cleanup left, is found by the tags and the names
+	return aws_operations.DeleteEKSClusterAll(ctx, cfg, name)
 }
 
 func (c *Cluster) Client() *kubernetes.Clientset {
@@ -229,3 +279,22 @@ func (c *Cluster) DumpDiagnostics(ctx context.Context, meta string) (string, err
 func (c *Cluster) IPFamily() clusters.IPFamily {
 	return c.ipFamily
 }
+
+// Region returns the region of the cluster, it is recorded in the environment state and passed to the token command
+// of the kubeconfig, which may run where AWS_REGION is not set or is set to another region
+func (c *Cluster) Region() string {
+	return c.awsCfg.Region
+}
+
+// Resources returns the IDs of the AWS resources created for the cluster, they are recorded in the environment state
+func (c *Cluster) Resources() map[string]string {
+	if c.resources == nil {
+		return nil
+	}
+	return map[string]string{
+		ResourceVpcID:            c.resources.VpcID,
+		ResourceClusterRoleArn:   c.resources.ClusterRoleArn,
+		ResourceNodeRoleArn:      c.resources.NodeRoleArn,
+		ResourceLaunchTemplateID: c.resources.LaunchTemplateID,
+	}
+}
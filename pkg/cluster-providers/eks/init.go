@@ -2,12 +2,15 @@ package eks
 
 import (
 	"context"
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/blang/semver/v4"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
 	"github.com/kris-nova/logger"
 	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
-	err_pkg "github.com/pkg/errors"
-	"github.com/spf13/cobra"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers/eks/aws-operations"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
 	"io"
 )
 
@@ -18,31 +21,117 @@ const (
 	eksClusterType clusters.Type = "eks"
 )
 
+// keys of the resources of an EKS cluster recorded in the environment state
+const (
+	ResourceVpcID            = "vpcId"
+	ResourceClusterRoleArn   = "clusterRoleArn"
+	ResourceNodeRoleArn      = "nodeRoleArn"
+	ResourceLaunchTemplateID = "launchTemplateId"
+)
+
 type eksProvider struct{}
 
-func (eksProvider) ClusterProvider(_ *cobra.Command, envName string) (clusters.Builder, error) {
-	err := guardOnEnv()
-	if err != nil {
+func (eksProvider) ClusterProvider(ctx context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	// fail before building when the credentials are missing
+	if _, err := loadAWSConfig(ctx, opts.Region); err != nil {
 		return nil, err
 	}
 
 	eksBuilder := NewBuilder()
 	eksBuilder.Name = envName
+	if !opts.KubernetesVersion.EQ(semver.Version{}) {
+		eksBuilder.WithClusterVersion(opts.KubernetesVersion)
+	}
+	if opts.NodeType != "" {
+		eksBuilder.WithNodeMachineType(opts.NodeType)
+	}
+	if opts.NodeCount > 0 {
+		eksBuilder.WithNodeCount(opts.NodeCount)
+	}
+	if opts.Region != "" {
+		eksBuilder.WithRegion(opts.Region)
+	}
+	if len(opts.Labels) > 0 {
+		eksBuilder.WithLabels(opts.Labels)
+	}
+	if (opts.VpcID == "") != (len(opts.SubnetIDs) == 0) {
+		return nil, errors.New("an existing VPC and its subnets must be set together")
+	}
+	if opts.VpcID != "" {
+		eksBuilder.WithExistingNetwork(opts.VpcID, opts.SubnetIDs)
+	}
+	switch opts.IPFamily {
+	case "", clusters.IPv4:
+	case clusters.IPv6:
+		eksBuilder.WithIPFamily(opts.IPFamily)
+	default:
+		return nil, errors.Errorf("EKS clusters do not support the %s IP family", opts.IPFamily)
+	}
 
 	return eksBuilder, nil
 }
 
-func (eksProvider) NewFromExisting(ctx context.Context, _ *cobra.Command, envName string) (clusters.Cluster, error) {
-	err := guardOnEnv()
+func (eksProvider) NewFromExisting(ctx context.Context, envName string, opts cluster_providers.Options) (clusters.Cluster, error) {
+	cfg, err := loadAWSConfig(ctx, opts.Region)
 	if err != nil {
 		return nil, err
 	}
+	return InitFromExisting(ctx, cfg, envName)
+}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+// CleanupLeftovers cleans up the resources of a cluster that is already gone, or whose creation was interrupted
+// before it became reachable
+func (eksProvider) CleanupLeftovers(ctx context.Context, envName string, opts cluster_providers.Options) error {
+	cfg, err := loadAWSConfig(ctx, opts.Region)
 	if err != nil {
-		return nil, err_pkg.Wrap(err, "failed to load AWS SDK config")
+		return err
+	}
+	return cleanupCluster(ctx, cfg, envName)
+}
+
+// Token generates an STS token of the cluster, the tokens embedded in kubeconfigs would expire within 15 minutes
+func (eksProvider) Token(ctx context.Context, envName string, opts cluster_providers.Options) (*oauth2.Token, error) {
+	cfg, err := loadAWSConfig(ctx, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+	return aws_operations.GenerateToken(ctx, cfg, envName)
+}
+
+func (eksProvider) List(ctx context.Context, opts cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	cfg, err := loadAWSConfig(ctx, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+	smokeClusters, err := aws_operations.ListSmokeClusters(ctx, cfg, utils.EnvNamePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []cluster_providers.Environment
+	for _, cluster := range smokeClusters {
+		envs = append(envs, cluster_providers.Environment{
+			Name:              cluster.Name,
+			CreatedAt:         cluster.CreatedAt,
+			KubernetesVersion: cluster.KubernetesVersion,
+		})
+	}
+	return envs, nil
+}
+
+func (eksProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	nodeType := flags.String("eks-node-type", defaultNodeMachineType, "The EC2 instance type of the nodes of EKS clusters")
+	nodeCount := flags.Int("eks-node-count", defaultNodeCount, "The number of nodes in the node group of EKS clusters")
+	vpcID := flags.String("eks-vpc-id", "", "An existing VPC to create EKS clusters in instead of creating one per cluster, "+
+		"cleaning up the clusters leaves it alone. It requires --eks-subnet-ids")
+	subnetIDs := flags.StringSlice("eks-subnet-ids", nil, "The subnets of --eks-vpc-id to create EKS clusters in, "+
+		"they must be in at least two availability zones and assign public IPs to the nodes")
+	return func(opts *cluster_providers.Options) {
+		opts.NodeType = *nodeType
+		opts.NodeCount = *nodeCount
+		opts.VpcID = *vpcID
+		opts.SubnetIDs = *subnetIDs
 	}
-	return InitFromExisting(ctx, cfg, envName)
 }
 
 func init() {
@@ -0,0 +1,20 @@
+package existing
+
+import (
+	"context"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+)
+
+// Builder adopts an existing cluster instead of creating one, so that the commands deploying
+// environments also work with clusters that kuma-smoke did not create
+type Builder struct {
+	Name string
+
+	kubeconfig  string
+	kubeContext string
+}
+
+// Build connects to the existing cluster and names it after the environment
+func (b *Builder) Build(_ context.Context) (clusters.Cluster, error) {
+	return newFromKubeconfig(b.Name, b.kubeconfig, b.kubeContext)
+}
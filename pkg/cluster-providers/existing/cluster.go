@@ -0,0 +1,273 @@
+package existing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	err_pkg "github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const existingClusterType clusters.Type = "existing"
+
+var (
+	// smokeNamespaces are the namespaces that the smoke tests create their workloads in
+	smokeNamespaces = []string{"kuma-test", "kic"}
+	// kumaNamespaces are the system namespaces of the tested products
+	kumaNamespaces = []string{"kuma-system", "kong-mesh-system"}
+	// kumaSelector matches the cluster scoped resources installed along with the tested products
+	kumaSelector = "app.kubernetes.io/name in (kuma,kong-mesh)"
+	// kumaCRDGroupSuffix is the suffix of the names of the CRDs installed by the tested products
+	kumaCRDGroupSuffix = ".kuma.io"
+
+	crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+)
+
+// Cluster is a clusters.Cluster implementation backed by a cluster that kuma-smoke did not create,
+// so cleaning it up only removes what the smoke tests installed rather than deleting the cluster
+type Cluster struct {
+	name string
+	// kubeconfig and kubeContext are recorded, so that the cluster is found again rather than the current context
+	// of the default kubeconfig, which may point to another cluster by then
+	kubeconfig  string
+	kubeContext string
+	client      *kubernetes.Clientset
+	cfg         *rest.Config
+	addons      clusters.Addons
+	l           *sync.RWMutex
+
+	ipFamily cluster_providers.IPFamilyDetector
+}
+
+// newFromKubeconfig adopts the cluster of a kubeconfig context, the default loading rules of kubectl are used
+// when kubeconfig is empty and the current context is used when kubeContext is empty
+func newFromKubeconfig(name, kubeconfig, kubeContext string) (*Cluster, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		absPath, err := filepath.Abs(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfig = absPath
+	}
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	restCfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err_pkg.Wrap(err, "failed to load the kubeconfig of the existing cluster")
+	}
+	if kubeContext == "" {
+		rawConfig, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, err_pkg.Wrap(err, "failed to load the kubeconfig of the existing cluster")
+		}
+		kubeContext = rawConfig.CurrentContext
+	}
+
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cluster{
+		name:        name,
+		kubeconfig:  kubeconfig,
+		kubeContext: kubeContext,
+		client:      client,
+		cfg:         restCfg,
+		addons:      make(clusters.Addons),
+		l:           &sync.RWMutex{},
+	}, nil
+}
+
+func (c *Cluster) Name() string {
+	return c.name
+}
+
+func (c *Cluster) Type() clusters.Type {
+	return existingClusterType
+}
+
+func (c *Cluster) Version() (semver.Version, error) {
+	versionInfo, err := c.Client().ServerVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return semver.Parse(strings.TrimPrefix(versionInfo.String(), "v"))
+}
+
+// Cleanup removes the namespaces created by the smoke tests and Kuma, the cluster itself and anything else on it are kept.
+// It keeps going when removing a resource fails, so that as much as possible is removed.
+func (c *Cluster) Cleanup(ctx context.Context) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	listOpts := metav1.ListOptions{LabelSelector: kumaSelector}
+	// the webhooks go first, otherwise they would block deleting resources once the control plane is gone
+	errs := []error{
+		c.client.AdmissionregistrationV1().MutatingWebhookConfigurations().DeleteCollection(ctx, metav1.DeleteOptions{}, listOpts),
+		c.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().DeleteCollection(ctx, metav1.DeleteOptions{}, listOpts),
+	}
+
+	namespaces := append(append([]string{}, smokeNamespaces...), kumaNamespaces...)
+	for _, ns := range namespaces {
+		if err := c.client.CoreV1().Namespaces().Delete(ctx, ns, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs,
+		c.client.RbacV1().ClusterRoleBindings().DeleteCollection(ctx, metav1.DeleteOptions{}, listOpts),
+		c.client.RbacV1().ClusterRoles().DeleteCollection(ctx, metav1.DeleteOptions{}, listOpts),
+		c.deleteKumaCRDs(ctx),
+	)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return c.waitForNamespacesDeleted(ctx, namespaces)
+}
+
+func (c *Cluster) deleteKumaCRDs(ctx context.Context) error {
+	dynamicClient, err := dynamic.NewForConfig(c.cfg)
+	if err != nil {
+		return err
+	}
+
+	crds, err := dynamicClient.Resource(crdResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err_pkg.Wrap(err, "failed to list CRDs")
+	}
+
+	var errs []error
+	for _, crd := range crds.Items {
+		if !strings.HasSuffix(crd.GetName(), kumaCRDGroupSuffix) {
+			continue
+		}
+		if err := dynamicClient.Resource(crdResource).Delete(ctx, crd.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForNamespacesDeleted waits for the finalizers of the namespaces, so that the smoke tests can run again right after
+func (c *Cluster) waitForNamespacesDeleted(ctx context.Context, namespaces []string) error {
+	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		for _, ns := range namespaces {
+			_, err := c.client.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+			if err == nil {
+				return false, nil
+			}
+			if !apierrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+func (c *Cluster) Client() *kubernetes.Clientset {
+	return c.client
+}
+
+func (c *Cluster) Config() *rest.Config {
+	return c.cfg
+}
+
+func (c *Cluster) GetAddon(name clusters.AddonName) (clusters.Addon, error) {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	for addonName, addon := range c.addons {
+		if addonName == name {
+			return addon, nil
+		}
+	}
+
+	return nil, fmt.Errorf("addon %s not found", name)
+}
+
+func (c *Cluster) ListAddons() []clusters.Addon {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	addonList := make([]clusters.Addon, 0, len(c.addons))
+	for _, v := range c.addons {
+		addonList = append(addonList, v)
+	}
+
+	return addonList
+}
+
+func (c *Cluster) DeployAddon(ctx context.Context, addon clusters.Addon) error {
+	c.l.Lock()
+	if _, ok := c.addons[addon.Name()]; ok {
+		c.l.Unlock()
+		return fmt.Errorf("addon component %s is already loaded into cluster %s", addon.Name(), c.Name())
+	}
+	c.addons[addon.Name()] = addon
+	c.l.Unlock()
+
+	return addon.Deploy(ctx, c)
+}
+
+func (c *Cluster) DeleteAddon(ctx context.Context, addon clusters.Addon) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if _, ok := c.addons[addon.Name()]; !ok {
+		return nil
+	}
+
+	if err := addon.Delete(ctx, c); err != nil {
+		return err
+	}
+
+	delete(c.addons, addon.Name())
+
+	return nil
+}
+
+// DumpDiagnostics produces diagnostics data for the cluster at a given time.
+// It returns the path to directory containing all the diagnostic files and an error.
+func (c *Cluster) DumpDiagnostics(ctx context.Context, meta string) (string, error) {
+	outDir, err := os.MkdirTemp(os.TempDir(), clusters.DiagnosticOutDirectoryPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	err = clusters.DumpDiagnostics(ctx, c, meta, outDir)
+	return outDir, err
+}
+
+// Resources returns the kubeconfig and the context of the cluster, they are recorded in the environment state.
+// The kubeconfig is empty when the cluster was found by the default loading rules of kubectl.
+func (c *Cluster) Resources() map[string]string {
+	resources := map[string]string{cluster_providers.ResourceKubeContext: c.kubeContext}
+	if c.kubeconfig != "" {
+		resources[cluster_providers.ResourceKubeconfig] = c.kubeconfig
+	}
+	return resources
+}
+
+// IPFamily is detected from the cluster, since an existing cluster may have been created with any IP family
+func (c *Cluster) IPFamily() clusters.IPFamily {
+	return c.ipFamily.IPFamily(c.client)
+}
@@ -0,0 +1,57 @@
+package existing
+
+import (
+	"context"
+	"errors"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/spf13/pflag"
+)
+
+// existingProvider adopts any cluster from a kubeconfig. The environment name is only a label of the cluster,
+// the cluster is located by Options.Kubeconfig and Options.KubeContext, which fall back to
+// the default loading rules of kubectl (e.g. the KUBECONFIG environment variable) and the current context.
+type existingProvider struct{}
+
+// ClusterProvider ignores the Kubernetes version, since the version of an existing cluster can't be chosen
+func (existingProvider) ClusterProvider(_ context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	if opts.NodeType != "" || opts.NodeCount > 1 || opts.Region != "" || len(opts.Labels) > 0 {
+		return nil, errors.New("existing clusters do not support node type, node count, region or labels")
+	}
+	if opts.IPFamily != "" && opts.IPFamily != clusters.IPv4 {
+		return nil, errors.New("the IP family of existing clusters can't be chosen, it is detected from the cluster")
+	}
+
+	return &Builder{
+		Name:        envName,
+		kubeconfig:  opts.Kubeconfig,
+		kubeContext: opts.KubeContext,
+	}, nil
+}
+
+func (existingProvider) NewFromExisting(_ context.Context, envName string, opts cluster_providers.Options) (clusters.Cluster, error) {
+	return newFromKubeconfig(envName, opts.Kubeconfig, opts.KubeContext)
+}
+
+// List finds nothing, the existing clusters are not created by kuma-smoke, so they must never be reaped
+func (existingProvider) List(_ context.Context, _ cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	return nil, nil
+}
+
+func (existingProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	kubeconfig := flags.String("existing-kubeconfig", "", "The kubeconfig of the existing cluster, the default loading rules of kubectl are used when not set")
+	kubeContext := flags.String("existing-context", "", "The kubeconfig context of the existing cluster, the current context is used when not set")
+	// the flags don't replace the kubeconfig and the context already restored from the state of the environment
+	return func(opts *cluster_providers.Options) {
+		if *kubeconfig != "" {
+			opts.Kubeconfig = *kubeconfig
+		}
+		if *kubeContext != "" {
+			opts.KubeContext = *kubeContext
+		}
+	}
+}
+
+func init() {
+	cluster_providers.Register("existing", existingProvider{})
+}
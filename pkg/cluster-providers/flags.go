@@ -0,0 +1,64 @@
+package cluster_providers
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"strings"
+)
+
+// FlagContributor is implemented by the providers that have their own flags on the commands creating clusters
+type FlagContributor interface {
+	// AddFlags adds the flags of the provider, the names of the flags must start with the name of the provider,
+	// e.g. "--eks-node-type". The returned function fills the options from the values of the flags.
+	AddFlags(flags *pflag.FlagSet) func(opts *Options)
+}
+
+// ProviderFlags are the flags that all the providers added to a command
+type ProviderFlags struct {
+	flags    *pflag.FlagSet
+	appliers map[string]func(opts *Options)
+}
+
+// AddProviderFlags adds the flags of all the registered providers to flags
+func AddProviderFlags(flags *pflag.FlagSet) *ProviderFlags {
+	providerFlags := &ProviderFlags{
+		flags:    flags,
+		appliers: map[string]func(opts *Options){},
+	}
+	for _, name := range SupportedProviderNames {
+		contributor, ok := supportedClusterProviders[name].(FlagContributor)
+		if !ok {
+			continue
+		}
+
+		providerFlagSet := pflag.NewFlagSet(name, pflag.ContinueOnError)
+		providerFlags.appliers[name] = contributor.AddFlags(providerFlagSet)
+		providerFlagSet.VisitAll(func(flag *pflag.Flag) {
+			if !strings.HasPrefix(flag.Name, name+"-") {
+				panic(fmt.Sprintf("flag --%s of provider %s is not namespaced with the name of the provider", flag.Name, name))
+			}
+		})
+		flags.AddFlagSet(providerFlagSet)
+	}
+	return providerFlags
+}
+
+// Apply fills opts from the flags of the provider, it fails when a flag of another provider is set
+func (f *ProviderFlags) Apply(providerName string, opts *Options) error {
+	var err error
+	f.flags.Visit(func(flag *pflag.Flag) {
+		for name := range f.appliers {
+			if name != providerName && strings.HasPrefix(flag.Name, name+"-") && err == nil {
+				err = fmt.Errorf("flag --%s only applies to platform %s", flag.Name, name)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if apply, ok := f.appliers[providerName]; ok {
+		apply(opts)
+	}
+	return nil
+}
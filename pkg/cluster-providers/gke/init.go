@@ -1,42 +1,170 @@
 package gke
 
 import (
+	container "cloud.google.com/go/container/apiv1"
+	"cloud.google.com/go/container/apiv1/containerpb"
 	"context"
 	"errors"
+	"fmt"
+	"github.com/blang/semver/v4"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters/types/gke"
 	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
-	"github.com/spf13/cobra"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	err_pkg "github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"google.golang.org/api/option"
 	"os"
+	"slices"
+	"strings"
+	"time"
 )
 
 type gkeProvider struct{}
 
-func (gkeProvider) ClusterProvider(_ *cobra.Command, envName string) (clusters.Builder, error) {
-	// todo: print help information to make these env vars more discoverable
-	gkeJsonCreds := os.Getenv(gke.GKECredsVar)
-	if gkeJsonCreds == "" {
-		return nil, errors.New(gke.GKECredsVar + " is not set")
+const defaultNodeMachineType = "e2-standard-16"
+
+func (gkeProvider) ClusterProvider(ctx context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	gkeJsonCreds, gkeProject, gkeLocation, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
 	}
-	gkeProject := os.Getenv(gke.GKEProjectVar)
-	if gkeProject == "" {
-		return nil, errors.New(gke.GKEProjectVar + " is not set")
+	// the node pool created by the builder always has a single node
+	if opts.NodeCount > 1 {
+		return nil, errors.New("GKE clusters do not support more than one node")
 	}
-	gkeLocation := os.Getenv(gke.GKELocationVar)
-	if gkeLocation == "" {
-		return nil, errors.New(gke.GKELocationVar + " is not set")
+	if opts.IPFamily != "" && opts.IPFamily != clusters.IPv4 {
+		return nil, errors.New("GKE clusters only support the ipv4 IP family")
 	}
 
 	gkeBuilder := gke.NewBuilder([]byte(gkeJsonCreds), gkeProject, gkeLocation)
 	gkeBuilder.Name = envName
-	gkeBuilder.WithNodeMachineType("e2-standard-16")
+	gkeBuilder.WithNodeMachineType(defaultNodeMachineType)
 	gkeBuilder.WithNetworkPolicy()
+	if opts.NodeType != "" {
+		gkeBuilder.WithNodeMachineType(opts.NodeType)
+	}
+	if !opts.KubernetesVersion.EQ(semver.Version{}) {
+		err := validateKubernetesVersion(ctx, gkeJsonCreds, gkeProject, gkeLocation, opts.KubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		// the latest patch of the minor version is deployed
+		gkeBuilder.WithClusterMinorVersion(opts.KubernetesVersion.Major, opts.KubernetesVersion.Minor)
+	}
+	if len(opts.Labels) > 0 {
+		gkeBuilder.WithLabels(opts.Labels)
+	}
 
 	return gkeBuilder, nil
 }
 
-func (gkeProvider) NewFromExisting(ctx context.Context, _ *cobra.Command, envName string) (clusters.Cluster, error) {
-	return gke.NewFromExistingWithEnv(ctx, envName)
+// validateKubernetesVersion checks that GKE offers the minor version of Kubernetes in the location
+func validateKubernetesVersion(ctx context.Context, gkeJsonCreds, gkeProject, gkeLocation string, version semver.Version) error {
+	mgrc, err := container.NewClusterManagerClient(ctx, option.WithCredentialsJSON([]byte(gkeJsonCreds)))
+	if err != nil {
+		return err_pkg.Wrap(err, "failed to create GKE cluster manager client")
+	}
+	defer mgrc.Close()
+
+	resp, err := mgrc.GetServerConfig(ctx, &containerpb.GetServerConfigRequest{
+		Name: fmt.Sprintf("projects/%s/locations/%s", gkeProject, gkeLocation),
+	})
+	if err != nil {
+		return err_pkg.Wrap(err, "failed to get the GKE server config")
+	}
+
+	minor := fmt.Sprintf("%d.%d", version.Major, version.Minor)
+	var available []string
+	for _, masterVersion := range resp.GetValidMasterVersions() {
+		v, err := semver.Parse(masterVersion)
+		if err != nil {
+			continue
+		}
+		availableMinor := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		if availableMinor == minor {
+			return nil
+		}
+		if !slices.Contains(available, availableMinor) {
+			available = append(available, availableMinor)
+		}
+	}
+	return err_pkg.Errorf("Kubernetes %s is not available on GKE in location %s, available versions are: %s",
+		minor, gkeLocation, strings.Join(available, ", "))
+}
+
+func (gkeProvider) NewFromExisting(ctx context.Context, envName string, opts cluster_providers.Options) (clusters.Cluster, error) {
+	gkeJsonCreds, gkeProject, gkeLocation, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+	return gke.NewFromExisting(ctx, envName, gkeProject, gkeLocation, []byte(gkeJsonCreds))
+}
+
+func (gkeProvider) List(ctx context.Context, opts cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	gkeJsonCreds, gkeProject, gkeLocation, err := settingsFromEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mgrc, err := container.NewClusterManagerClient(ctx, option.WithCredentialsJSON([]byte(gkeJsonCreds)))
+	if err != nil {
+		return nil, err_pkg.Wrap(err, "failed to create GKE cluster manager client")
+	}
+	defer mgrc.Close()
+
+	resp, err := mgrc.ListClusters(ctx, &containerpb.ListClustersRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", gkeProject, gkeLocation),
+	})
+	if err != nil {
+		return nil, err_pkg.Wrap(err, "failed to list GKE clusters")
+	}
+
+	var envs []cluster_providers.Environment
+	for _, cluster := range resp.GetClusters() {
+		if !strings.HasPrefix(cluster.GetName(), utils.EnvNamePrefix) {
+			continue
+		}
+
+		env := cluster_providers.Environment{
+			Name:              cluster.GetName(),
+			KubernetesVersion: cluster.GetCurrentMasterVersion(),
+		}
+		if createdAt, err := time.Parse(time.RFC3339, cluster.GetCreateTime()); err == nil {
+			env.CreatedAt = createdAt
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (gkeProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	machineType := flags.String("gke-machine-type", defaultNodeMachineType, "The machine type of the nodes of GKE clusters")
+	return func(opts *cluster_providers.Options) {
+		opts.NodeType = *machineType
+	}
+}
+
+// settingsFromEnv reads the credentials, the project and the location from the environment variables,
+// the location is overridden by the region of the options
+func settingsFromEnv(opts cluster_providers.Options) (string, string, string, error) {
+	// todo: print help information to make these env vars more discoverable
+	gkeJsonCreds := os.Getenv(gke.GKECredsVar)
+	if gkeJsonCreds == "" {
+		return "", "", "", errors.New(gke.GKECredsVar + " is not set")
+	}
+	gkeProject := os.Getenv(gke.GKEProjectVar)
+	if gkeProject == "" {
+		return "", "", "", errors.New(gke.GKEProjectVar + " is not set")
+	}
+	gkeLocation := os.Getenv(gke.GKELocationVar)
+	if opts.Region != "" {
+		gkeLocation = opts.Region
+	}
+	if gkeLocation == "" {
+		return "", "", "", errors.New(gke.GKELocationVar + " is not set")
+	}
+	return gkeJsonCreds, gkeProject, gkeLocation, nil
 }
 
 func init() {
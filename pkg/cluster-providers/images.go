@@ -0,0 +1,34 @@
+package cluster_providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ImageLoader is implemented by the providers of local clusters that images can be side-loaded into,
+// so that images that are not published yet (e.g. built from a branch of Kuma) can be tested
+type ImageLoader interface {
+	// LoadImages loads images into all the nodes of the cluster named envName,
+	// an image is either an image archive created by "docker save" or a reference to an image of the local docker daemon
+	LoadImages(ctx context.Context, envName string, images []string) error
+}
+
+func LoadImages(ctx context.Context, providerName string, envName string, images []string) error {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return err
+	}
+
+	loader, ok := provider.(ImageLoader)
+	if !ok {
+		return fmt.Errorf("loading images is not supported on platform %s", providerName)
+	}
+	return loader.LoadImages(ctx, envName, images)
+}
+
+// IsImageArchive tells an image archive from an image reference by checking whether the file exists
+func IsImageArchive(image string) bool {
+	info, err := os.Stat(image)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,92 @@
+package cluster_providers
+
+import (
+	"context"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// ParseIPFamily parses the IP family of the clusters to create, an empty string means the default of IPv4
+func ParseIPFamily(family string) (clusters.IPFamily, error) {
+	switch ipFamily := clusters.IPFamily(strings.ToLower(family)); ipFamily {
+	case "", clusters.IPv4:
+		return clusters.IPv4, nil
+	case clusters.IPv6, clusters.Dual:
+		return ipFamily, nil
+	default:
+		return "", errors.Errorf("invalid IP family %q, it must be one of %s, %s and %s", family, clusters.IPv4, clusters.IPv6, clusters.Dual)
+	}
+}
+
+// DetectIPFamily detects the IP family of a running cluster from the pod CIDRs of its nodes. The cluster IPs of the
+// "kubernetes" service are used instead when the CNI does not allocate pod CIDRs to nodes, e.g. the VPC CNI on EKS.
+func DetectIPFamily(ctx context.Context, client kubernetes.Interface) (clusters.IPFamily, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list nodes")
+	}
+	var cidrs []string
+	for _, node := range nodes.Items {
+		cidrs = append(cidrs, node.Spec.PodCIDRs...)
+	}
+	if len(cidrs) > 0 {
+		return ipFamilyOf(cidrs)
+	}
+
+	svc, err := client.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the kubernetes service")
+	}
+	return ipFamilyOf(svc.Spec.ClusterIPs)
+}
+
+// IPFamilyDetector detects the IP family of a cluster once for the clusters.Cluster implementations that
+// can't tell it from how the cluster was created, e.g. the clusters that were created by another process
+type IPFamilyDetector struct {
+	once   sync.Once
+	family clusters.IPFamily
+}
+
+// IPFamily returns the detected IP family, it falls back to IPv4 when the cluster is unreachable,
+// e.g. when a broken cluster is being cleaned up
+func (d *IPFamilyDetector) IPFamily(client kubernetes.Interface) clusters.IPFamily {
+	d.once.Do(func() {
+		d.family = clusters.IPv4
+		if family, err := DetectIPFamily(context.Background(), client); err == nil {
+			d.family = family
+		}
+	})
+	return d.family
+}
+
+// ipFamilyOf returns the IP family of a set of IP addresses or CIDRs
+func ipFamilyOf(addresses []string) (clusters.IPFamily, error) {
+	families := map[corev1.IPFamily]bool{}
+	for _, address := range addresses {
+		ip, _, _ := strings.Cut(address, "/")
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid IP address %q", address)
+		}
+		if addr.Is4() {
+			families[corev1.IPv4Protocol] = true
+		} else {
+			families[corev1.IPv6Protocol] = true
+		}
+	}
+
+	switch {
+	case families[corev1.IPv4Protocol] && families[corev1.IPv6Protocol]:
+		return clusters.Dual, nil
+	case families[corev1.IPv6Protocol]:
+		return clusters.IPv6, nil
+	default:
+		return clusters.IPv4, nil
+	}
+}
@@ -0,0 +1,61 @@
+package k3d
+
+import (
+	"context"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+// Builder generates clusters.Cluster objects backed by k3d given
+// provided configuration options.
+type Builder struct {
+	Name string
+
+	clusterVersion *semver.Version
+	agents         int
+}
+
+const (
+	// network is the docker network shared by all the k3d clusters, so that the zones of
+	// a multizone environment are able to reach the global control plane through node ports
+	network = "kuma-smoke-k3d"
+	// k3sImageFormat is the k3s node image of a Kubernetes version, e.g. "rancher/k3s:v1.31.1-k3s1"
+	k3sImageFormat = "rancher/k3s:v%s-k3s1"
+)
+
+// WithClusterVersion configures the Kubernetes cluster version for the Builder
+// to use when building the k3d cluster.
+func (b *Builder) WithClusterVersion(version semver.Version) *Builder {
+	b.clusterVersion = &version
+	return b
+}
+
+// WithAgents configures the number of agent nodes besides the server node.
+func (b *Builder) WithAgents(agents int) *Builder {
+	b.agents = agents
+	return b
+}
+
+// Build creates and configures clients for a k3d-based Kubernetes clusters.Cluster.
+// The bundled Traefik ingress controller and ServiceLB are kept, so that the tests run against a stock k3s.
+func (b *Builder) Build(ctx context.Context) (clusters.Cluster, error) {
+	args := []string{"cluster", "create", b.Name,
+		"--agents", strconv.Itoa(b.agents),
+		"--network", network,
+		"--wait",
+		"--kubeconfig-update-default=false",
+		"--kubeconfig-switch-context=false",
+	}
+	if b.clusterVersion != nil {
+		args = append(args, "--image", fmt.Sprintf(k3sImageFormat, b.clusterVersion.String()))
+	}
+
+	if _, err := runK3d(ctx, args...); err != nil {
+		return nil, errors.Wrapf(err, "failed to create k3d cluster %s", b.Name)
+	}
+
+	return newFromExisting(ctx, b.Name)
+}
@@ -0,0 +1,99 @@
+package k3d
+
+import (
+	"context"
+	"errors"
+	"github.com/blang/semver/v4"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	err_pkg "github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// labels that k3d puts on the node containers of a cluster
+	k3dClusterLabel = "k3d.cluster"
+	k3dRoleLabel    = "k3d.role"
+
+	// dockerTimeFormat is the format of the creation time printed by "docker ps"
+	dockerTimeFormat = "2006-01-02 15:04:05 -0700 MST"
+)
+
+type k3dProvider struct{}
+
+func (k3dProvider) ClusterProvider(_ context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	if opts.NodeType != "" || opts.Region != "" || len(opts.Labels) > 0 {
+		return nil, errors.New("k3d clusters do not support node type, region or labels")
+	}
+	if opts.IPFamily != "" && opts.IPFamily != clusters.IPv4 {
+		return nil, errors.New("k3d clusters only support the ipv4 IP family")
+	}
+
+	builder := &Builder{Name: envName}
+	if !opts.KubernetesVersion.EQ(semver.Version{}) {
+		builder.WithClusterVersion(opts.KubernetesVersion)
+	}
+	// the single server node also runs workloads, so the other nodes are agents
+	if opts.NodeCount > 1 {
+		builder.WithAgents(opts.NodeCount - 1)
+	}
+	return builder, nil
+}
+
+func (k3dProvider) NewFromExisting(ctx context.Context, envName string, _ cluster_providers.Options) (clusters.Cluster, error) {
+	return newFromExisting(ctx, envName)
+}
+
+// List finds the k3d clusters by their server containers, the Kubernetes version is taken from the node image tag
+func (k3dProvider) List(ctx context.Context, _ cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	cmd := exec.CommandContext(ctx, "docker", "ps", "--all",
+		"--filter", "label="+k3dRoleLabel+"=server",
+		"--format", `{{.Label "`+k3dClusterLabel+`"}}\t{{.CreatedAt}}\t{{.Image}}`)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err_pkg.Wrap(err, "failed to list k3d node containers")
+	}
+
+	var envs []cluster_providers.Environment
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], utils.EnvNamePrefix) {
+			continue
+		}
+
+		env := cluster_providers.Environment{Name: fields[0]}
+		if createdAt, err := time.Parse(dockerTimeFormat, fields[1]); err == nil {
+			env.CreatedAt = createdAt
+		}
+		// the image is in the form of "rancher/k3s:v1.31.1-k3s1"
+		if i := strings.LastIndex(fields[2], ":"); i > strings.LastIndex(fields[2], "/") {
+			env.KubernetesVersion = strings.TrimPrefix(fields[2][i+1:], "v")
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+// LoadImages loads images with k3d, which tells image archives from image references by itself
+func (k3dProvider) LoadImages(ctx context.Context, envName string, images []string) error {
+	args := append([]string{"image", "import"}, images...)
+	if _, err := runK3d(ctx, append(args, "--cluster", envName)...); err != nil {
+		return err_pkg.Wrapf(err, "failed to load images %s into k3d cluster %s", strings.Join(images, ", "), envName)
+	}
+	return nil
+}
+
+func (k3dProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	nodes := flags.Int("k3d-nodes", 1, "The number of nodes of k3d clusters, the nodes besides the server node are agents")
+	return func(opts *cluster_providers.Options) {
+		opts.NodeCount = *nodes
+	}
+}
+
+func init() {
+	cluster_providers.Register("k3d", k3dProvider{})
+}
@@ -0,0 +1,36 @@
+package gke
+
+import (
+	"context"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+)
+
+// ipFamilyCluster is a kind cluster reporting its IP family, KTF only knows it for the IPv6 only clusters built by
+// KTF itself. The family is detected from the cluster when it is not known from the kind config.
+type ipFamilyCluster struct {
+	clusters.Cluster
+	ipFamily clusters.IPFamily
+	detector cluster_providers.IPFamilyDetector
+}
+
+func (c *ipFamilyCluster) IPFamily() clusters.IPFamily {
+	if c.ipFamily != "" {
+		return c.ipFamily
+	}
+	return c.detector.IPFamily(c.Client())
+}
+
+// ipFamilyBuilder builds kind clusters reporting the IP family of the kind config they are created with
+type ipFamilyBuilder struct {
+	clusters.Builder
+	ipFamily clusters.IPFamily
+}
+
+func (b *ipFamilyBuilder) Build(ctx context.Context) (clusters.Cluster, error) {
+	cluster, err := b.Builder.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFamilyCluster{Cluster: cluster, ipFamily: b.ipFamily}, nil
+}
@@ -0,0 +1,119 @@
+package gke
+
+import (
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"os"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// clusterConfig generates the kind configuration of a cluster, it is nil when the default single node IPv4 cluster
+// of kind is good enough. The nodes that workloads are scheduled on get the node labels and the node taints,
+// they are the workers, or the control plane node of a single node cluster.
+func clusterConfig(opts cluster_providers.Options) (*v1alpha4.Cluster, error) {
+	defaultIPFamily := opts.IPFamily == "" || opts.IPFamily == clusters.IPv4
+	if opts.ConfigFile != "" {
+		if opts.NodeCount > 1 || len(opts.NodeLabels) > 0 || len(opts.NodeTaints) > 0 || !defaultIPFamily {
+			return nil, errors.New("a kind config file can't be combined with the number of nodes, node labels, node taints or IP family")
+		}
+		return loadClusterConfig(opts.ConfigFile)
+	}
+	if opts.NodeCount <= 1 && len(opts.NodeLabels) == 0 && len(opts.NodeTaints) == 0 && defaultIPFamily {
+		return nil, nil
+	}
+
+	var taints []corev1.Taint
+	for _, taint := range opts.NodeTaints {
+		t, err := parseTaint(taint)
+		if err != nil {
+			return nil, err
+		}
+		taints = append(taints, t)
+	}
+
+	config := &v1alpha4.Cluster{
+		TypeMeta: v1alpha4.TypeMeta{Kind: "Cluster", APIVersion: "kind.x-k8s.io/v1alpha4"},
+		Nodes:    []v1alpha4.Node{{Role: v1alpha4.ControlPlaneRole}},
+	}
+	if !defaultIPFamily {
+		config.Networking.IPFamily = v1alpha4.ClusterIPFamily(opts.IPFamily)
+		// the IPv6 loopback address of the API server is not reachable with Docker Desktop on macOS and Windows,
+		// see https://kind.sigs.k8s.io/docs/user/configuration/#ip-family
+		config.Networking.APIServerAddress = "127.0.0.1"
+	}
+	// the single control plane node also runs workloads, so the other nodes are workers
+	for i := 1; i < opts.NodeCount; i++ {
+		config.Nodes = append(config.Nodes, v1alpha4.Node{Role: v1alpha4.WorkerRole})
+	}
+
+	workloadNodes := config.Nodes[1:]
+	registration := "JoinConfiguration"
+	if len(workloadNodes) == 0 {
+		workloadNodes = config.Nodes
+		registration = "InitConfiguration"
+	}
+	for i := range workloadNodes {
+		workloadNodes[i].Labels = opts.NodeLabels
+		if len(taints) > 0 {
+			patch, err := taintsPatch(registration, taints)
+			if err != nil {
+				return nil, err
+			}
+			workloadNodes[i].KubeadmConfigPatches = []string{patch}
+		}
+	}
+	return config, nil
+}
+
+// loadClusterConfig reads a kind Cluster config file, which is passed through to kind as it is
+func loadClusterConfig(path string) (*v1alpha4.Cluster, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read kind config file %s", path)
+	}
+
+	config := &v1alpha4.Cluster{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kind config file %s", path)
+	}
+	if config.Kind != "Cluster" || config.APIVersion != "kind.x-k8s.io/v1alpha4" {
+		return nil, errors.Errorf("kind config file %s is not a kind.x-k8s.io/v1alpha4 Cluster", path)
+	}
+	return config, nil
+}
+
+// parseTaint parses a taint in the format of kubectl, e.g. "dedicated=smoke:NoSchedule" or "dedicated:NoSchedule"
+func parseTaint(taint string) (corev1.Taint, error) {
+	keyValue, effect, found := strings.Cut(taint, ":")
+	if !found {
+		return corev1.Taint{}, errors.Errorf("invalid taint %q, the format is key[=value]:effect", taint)
+	}
+
+	key, value, _ := strings.Cut(keyValue, "=")
+	t := corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)}
+	switch t.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return corev1.Taint{}, errors.Errorf("invalid taint %q, the effect must be one of %s, %s and %s", taint,
+			corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+	}
+	if t.Key == "" {
+		return corev1.Taint{}, errors.Errorf("invalid taint %q, the key must not be empty", taint)
+	}
+	return t, nil
+}
+
+// taintsPatch generates a kubeadm config patch registering a node with the taints
+func taintsPatch(registration string, taints []corev1.Taint) (string, error) {
+	patch, err := yaml.Marshal(map[string]interface{}{
+		"kind": registration,
+		"nodeRegistration": map[string]interface{}{
+			"taints": taints,
+		},
+	})
+	return string(patch), err
+}
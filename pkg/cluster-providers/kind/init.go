@@ -1,22 +1,160 @@
 package gke
 
 import (
+	"bytes"
 	"context"
+	"github.com/blang/semver/v4"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters/types/kind"
 	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
-	"github.com/spf13/cobra"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"os/exec"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+const (
+	// labels that kind puts on the node containers of a cluster
+	kindClusterLabel = "io.x-k8s.kind.cluster"
+	kindRoleLabel    = "io.x-k8s.kind.role"
+
+	// dockerTimeFormat is the format of the creation time printed by "docker ps"
+	dockerTimeFormat = "2006-01-02 15:04:05 -0700 MST"
 )
 
 type kindProvider struct{}
 
-func (kindProvider) ClusterProvider(_ *cobra.Command, _ string) (clusters.Builder, error) {
-	// kind builtin supported by KTF, so don't need to do anything here
-	return nil, nil
+func (kindProvider) ClusterProvider(_ context.Context, envName string, opts cluster_providers.Options) (clusters.Builder, error) {
+	if opts.NodeType != "" || opts.Region != "" || len(opts.Labels) > 0 {
+		return nil, errors.New("kind clusters do not support node type, region or labels")
+	}
+
+	builder := kind.NewBuilder().WithName(envName)
+	if !opts.KubernetesVersion.EQ(semver.Version{}) {
+		builder = builder.WithClusterVersion(opts.KubernetesVersion)
+	}
+
+	config, err := clusterConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	mirrored := opts.RegistryMirrors || len(opts.RegistrySeedArchives) > 0
+	if mirrored {
+		if config, err = withMirrors(config); err != nil {
+			return nil, err
+		}
+	}
+	if config != nil {
+		content, err := yaml.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		builder = builder.WithConfigReader(bytes.NewReader(content))
+	}
+
+	ipFamily := clusters.IPv4
+	if config != nil && config.Networking.IPFamily != "" {
+		ipFamily = clusters.IPFamily(config.Networking.IPFamily)
+	}
+	if mirrored {
+		return &ipFamilyBuilder{Builder: &mirroredBuilder{Builder: builder, seedArchives: opts.RegistrySeedArchives}, ipFamily: ipFamily}, nil
+	}
+	return &ipFamilyBuilder{Builder: builder, ipFamily: ipFamily}, nil
+}
+
+func (kindProvider) NewFromExisting(_ context.Context, envName string, _ cluster_providers.Options) (clusters.Cluster, error) {
+	cluster, err := kind.NewFromExisting(envName)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFamilyCluster{Cluster: cluster}, nil
+}
+
+// List finds the kind clusters by their control plane containers, the Kubernetes version is taken from the node image tag
+func (kindProvider) List(ctx context.Context, _ cluster_providers.Options) ([]cluster_providers.Environment, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "ps", "--all",
+		"--filter", "label="+kindRoleLabel+"=control-plane",
+		"--format", `{{.Label "`+kindClusterLabel+`"}}\t{{.CreatedAt}}\t{{.Image}}`)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list kind node containers: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var envs []cluster_providers.Environment
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], utils.EnvNamePrefix) {
+			continue
+		}
+
+		env := cluster_providers.Environment{Name: fields[0]}
+		if createdAt, err := time.Parse(dockerTimeFormat, fields[1]); err == nil {
+			env.CreatedAt = createdAt
+		}
+		// the image is in the form of "kindest/node:v1.31.0@sha256:..."
+		image, _, _ := strings.Cut(fields[2], "@")
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			env.KubernetesVersion = strings.TrimPrefix(image[i+1:], "v")
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+// LoadImages loads the image archives one by one and all the images of the local docker daemon at once
+func (kindProvider) LoadImages(ctx context.Context, envName string, images []string) error {
+	var refs []string
+	for _, image := range images {
+		if !cluster_providers.IsImageArchive(image) {
+			refs = append(refs, image)
+			continue
+		}
+		if err := runKind(ctx, "load", "image-archive", image, "--name", envName); err != nil {
+			return errors.Wrapf(err, "failed to load image archive %s into kind cluster %s", image, envName)
+		}
+	}
+
+	if len(refs) > 0 {
+		args := append([]string{"load", "docker-image"}, refs...)
+		if err := runKind(ctx, append(args, "--name", envName)...); err != nil {
+			return errors.Wrapf(err, "failed to load images %s into kind cluster %s", strings.Join(refs, ", "), envName)
+		}
+	}
+	return nil
+}
+
+func runKind(ctx context.Context, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "kind", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
 }
 
-func (kindProvider) NewFromExisting(_ context.Context, _ *cobra.Command, envName string) (clusters.Cluster, error) {
-	return kind.NewFromExisting(envName)
+func (kindProvider) AddFlags(flags *pflag.FlagSet) func(opts *cluster_providers.Options) {
+	nodes := flags.Int("kind-nodes", 1, "The number of nodes of kind clusters, the nodes besides the control plane node are workers")
+	nodeLabels := flags.StringToString("kind-node-labels", nil, "The labels of the nodes running workloads, e.g. topology.kubernetes.io/zone=zone-1")
+	nodeTaints := flags.StringSlice("kind-node-taints", nil, "The taints of the nodes running workloads in the format of key[=value]:effect, e.g. dedicated=smoke:NoSchedule")
+	configFile := flags.String("kind-config", "", "A kind Cluster config file passed through to kind, it can't be combined with the other --kind-* flags")
+	registryMirrors := flags.Bool("kind-registry-mirrors", false,
+		"Pull images through local pull-through cache registries, which are kept in docker volumes shared by all the kind clusters")
+	registrySeedArchives := flags.StringSlice("kind-registry-seed", nil,
+		"Image archives created by 'docker save' to pre-seed the local registry mirrors with, implies --kind-registry-mirrors")
+	return func(opts *cluster_providers.Options) {
+		opts.RegistryMirrors = *registryMirrors
+		opts.RegistrySeedArchives = *registrySeedArchives
+		opts.NodeCount = *nodes
+		opts.NodeLabels = *nodeLabels
+		opts.NodeTaints = *nodeTaints
+		opts.ConfigFile = *configFile
+	}
 }
 
 func init() {
@@ -0,0 +1,251 @@
+package gke
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/distribution/reference"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters/types/kind"
+	"github.com/pkg/errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"strings"
+	"sync"
+)
+
+// mirror is a pull-through cache of an upstream registry running in a local docker container. The cache is kept
+// in a docker volume that outlives the clusters, so repeated runs and offline runs do not hit the upstream registry.
+type mirror struct {
+	// host is the registry in the image references, e.g. "docker.io"
+	host string
+	// upstream is the URL that the registry is served at
+	upstream string
+}
+
+var mirrors = []mirror{
+	{host: "docker.io", upstream: "https://registry-1.docker.io"},
+	{host: "quay.io", upstream: "https://quay.io"},
+	{host: "ghcr.io", upstream: "https://ghcr.io"},
+	{host: "registry.k8s.io", upstream: "https://registry.k8s.io"},
+}
+
+const (
+	registryImage = "registry:2"
+	registryPort  = 5000
+	mirrorPrefix  = "kuma-smoke-mirror-"
+	// kindNetwork is the docker network that kind attaches the nodes of all the clusters to
+	kindNetwork = "kind"
+	// containerdHostsDir is where containerd on the nodes looks up the hosts.toml of each registry
+	containerdHostsDir = "/etc/containerd/certs.d"
+)
+
+// mirrorsLock serializes starting the mirrors shared by the clusters deployed in parallel, e.g. the zones of a multizone environment
+var mirrorsLock sync.Mutex
+
+// name is both the name of the container and of the volume of the mirror, the nodes reach the mirror by this name
+func (m mirror) name() string {
+	return mirrorPrefix + strings.ReplaceAll(m.host, ".", "-")
+}
+
+// mirroredBuilder builds kind clusters pulling images through the local mirrors
+type mirroredBuilder struct {
+	*kind.Builder
+	seedArchives []string
+}
+
+func (b *mirroredBuilder) Build(ctx context.Context) (clusters.Cluster, error) {
+	if err := startMirrors(ctx, b.seedArchives); err != nil {
+		return nil, err
+	}
+
+	cluster, err := b.Builder.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the network of kind only exists once a cluster is created
+	if err := connectMirrors(ctx); err != nil {
+		_ = cluster.Cleanup(ctx)
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// withMirrors configures containerd on all the nodes to pull through the mirrors, falling back to the upstream registries
+func withMirrors(config *v1alpha4.Cluster) (*v1alpha4.Cluster, error) {
+	hostsDir, err := writeContainerdHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = &v1alpha4.Cluster{
+			TypeMeta: v1alpha4.TypeMeta{Kind: "Cluster", APIVersion: "kind.x-k8s.io/v1alpha4"},
+			Nodes:    []v1alpha4.Node{{Role: v1alpha4.ControlPlaneRole}},
+		}
+	}
+	config.ContainerdConfigPatches = append(config.ContainerdConfigPatches, fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = %q
+`, containerdHostsDir))
+	for i := range config.Nodes {
+		config.Nodes[i].ExtraMounts = append(config.Nodes[i].ExtraMounts, v1alpha4.Mount{
+			HostPath:      hostsDir,
+			ContainerPath: containerdHostsDir,
+			Readonly:      true,
+		})
+	}
+	return config, nil
+}
+
+// writeContainerdHosts writes the hosts.toml of each mirrored registry into a directory mounted into the nodes
+func writeContainerdHosts() (string, error) {
+	hostsDir := filepath.Join(os.TempDir(), "kuma-smoke-containerd-hosts")
+	for _, m := range mirrors {
+		hosts := fmt.Sprintf("server = %q\n\n[host.\"http://%s:%d\"]\n  capabilities = [\"pull\", \"resolve\"]\n",
+			m.upstream, m.name(), registryPort)
+		if err := os.MkdirAll(filepath.Join(hostsDir, m.host), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(hostsDir, m.host, "hosts.toml"), []byte(hosts), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return hostsDir, nil
+}
+
+// startMirrors starts the mirrors that are not running yet and pre-seeds them with the images of the archives
+func startMirrors(ctx context.Context, seedArchives []string) error {
+	mirrorsLock.Lock()
+	defer mirrorsLock.Unlock()
+
+	for _, m := range mirrors {
+		running, err := runDocker(ctx, "container", "inspect", "--format", "{{.State.Running}}", m.name())
+		switch {
+		case err != nil:
+			_, err = runDocker(ctx, "run", "--detach", "--name", m.name(), "--restart", "unless-stopped",
+				"--volume", m.name()+":/var/lib/registry",
+				"--env", "REGISTRY_PROXY_REMOTEURL="+m.upstream,
+				registryImage)
+		case strings.TrimSpace(running) != "true":
+			_, err = runDocker(ctx, "start", m.name())
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to start the mirror of %s", m.host)
+		}
+	}
+
+	for _, archive := range seedArchives {
+		if err := seedMirrors(ctx, archive); err != nil {
+			return errors.Wrapf(err, "failed to pre-seed the mirrors with image archive %s", archive)
+		}
+	}
+	return nil
+}
+
+func connectMirrors(ctx context.Context) error {
+	for _, m := range mirrors {
+		_, err := runDocker(ctx, "network", "connect", kindNetwork, m.name())
+		if err != nil && !strings.Contains(err.Error(), "already exists") {
+			return errors.Wrapf(err, "failed to connect the mirror of %s to the %s network", m.host, kindNetwork)
+		}
+	}
+	return nil
+}
+
+// seedMirrors pushes the images of an archive into the volumes of the mirrors. A registry in proxy mode does not
+// accept pushes, so the images are pushed to a temporary registry sharing the volume with the mirror.
+func seedMirrors(ctx context.Context, archive string) error {
+	out, err := runDocker(ctx, "load", "--input", archive)
+	if err != nil {
+		return err
+	}
+
+	images := map[mirror][]reference.NamedTagged{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		// images without a tag are loaded as "Loaded image ID: sha256:...", they can't be pulled by a reference
+		image, found := strings.CutPrefix(scanner.Text(), "Loaded image: ")
+		if !found {
+			continue
+		}
+
+		named, err := reference.ParseNormalizedNamed(image)
+		if err != nil {
+			return err
+		}
+		tagged, ok := named.(reference.NamedTagged)
+		if !ok {
+			continue
+		}
+		m, err := mirrorOf(reference.Domain(named))
+		if err != nil {
+			return err
+		}
+		images[m] = append(images[m], tagged)
+	}
+
+	for m, tagged := range images {
+		if err := pushToMirror(ctx, m, tagged); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mirrorOf(host string) (mirror, error) {
+	for _, m := range mirrors {
+		if m.host == host {
+			return m, nil
+		}
+	}
+	return mirror{}, errors.Errorf("registry %s is not mirrored", host)
+}
+
+func pushToMirror(ctx context.Context, m mirror, images []reference.NamedTagged) error {
+	out, err := runDocker(ctx, "run", "--detach", "--rm",
+		"--volume", m.name()+":/var/lib/registry",
+		"--publish", fmt.Sprintf("127.0.0.1::%d", registryPort),
+		registryImage)
+	if err != nil {
+		return err
+	}
+	container := strings.TrimSpace(out)
+	defer func() {
+		_, _ = runDocker(context.Background(), "rm", "--force", container)
+	}()
+
+	out, err = runDocker(ctx, "port", container, fmt.Sprintf("%d/tcp", registryPort))
+	if err != nil {
+		return err
+	}
+	// the first line is the address on IPv4, e.g. "127.0.0.1:49153"
+	address, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+
+	for _, image := range images {
+		target := fmt.Sprintf("%s/%s:%s", address, reference.Path(image), image.Tag())
+		if _, err := runDocker(ctx, "tag", image.String(), target); err != nil {
+			return err
+		}
+		_, err := runDocker(ctx, "push", target)
+		_, _ = runDocker(ctx, "rmi", target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runDocker(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run 'docker %s': %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
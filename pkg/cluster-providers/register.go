@@ -3,13 +3,67 @@ package cluster_providers
 import (
 	"context"
 	"fmt"
+	"github.com/blang/semver/v4"
 	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
-	"github.com/spf13/cobra"
+	"time"
 )
 
+// Options configure the clusters of a provider, the zero value of each option means the default of the provider.
+// A provider returns an error for an option that it does not support rather than ignoring it.
+type Options struct {
+	// KubernetesVersion is the version of Kubernetes to deploy
+	KubernetesVersion semver.Version
+	// IPFamily is the IP family of the cluster networking, the clusters of all the providers support IPv4
+	IPFamily clusters.IPFamily
+	// NodeType is the machine type of the nodes, e.g. "e2-standard-16" on GKE or "c5.4xlarge" on EKS
+	NodeType string
+	// NodeCount is the number of nodes that workloads are scheduled on, the nodes of managed control planes are not counted
+	NodeCount int
+	// Region is where the clusters are located, it overrides the region set by the environment variables of the provider
+	Region string
+	// VpcID and SubnetIDs are existing networking that the clusters are created in instead of creating their own,
+	// cleaning up the clusters leaves it alone. Only the eks provider honors them.
+	VpcID     string
+	SubnetIDs []string
+	// Labels are put on the clusters as labels or tags, so that they can be found later
+	Labels map[string]string
+	// NodeLabels and NodeTaints are put on the nodes that workloads are scheduled on, taints are in the format of
+	// key[=value]:effect. Only the kind provider honors them.
+	NodeLabels map[string]string
+	NodeTaints []string
+	// ConfigFile is a configuration file of the clusters in the format of the platform, e.g. a kind Cluster config.
+	// Only the kind provider honors it.
+	ConfigFile string
+	// RegistryMirrors pulls images through local registry mirrors, which are pre-seeded with the images of
+	// RegistrySeedArchives. Only the kind provider honors them.
+	RegistryMirrors      bool
+	RegistrySeedArchives []string
+	// Kubeconfig and KubeContext locate a cluster that kuma-smoke did not create, only the existing provider honors them
+	Kubeconfig  string
+	KubeContext string
+}
+
 type ClusterProvider interface {
-	ClusterProvider(cmd *cobra.Command, envName string) (clusters.Builder, error)
-	NewFromExisting(ctx context.Context, cmd *cobra.Command, envName string) (clusters.Cluster, error)
+	// ClusterProvider returns the builder of a new cluster named envName
+	ClusterProvider(ctx context.Context, envName string, opts Options) (clusters.Builder, error)
+	// NewFromExisting connects to an existing cluster named envName
+	NewFromExisting(ctx context.Context, envName string, opts Options) (clusters.Cluster, error)
+	// List finds the clusters created by kuma-smoke that still exist on the platform
+	List(ctx context.Context, opts Options) ([]Environment, error)
+}
+
+// LeftoverCleaner is implemented by the providers that are able to clean up the resources of an environment whose
+// cluster is already gone or unreachable, e.g. the resources left by a failed cleanup or an interrupted creation
+type LeftoverCleaner interface {
+	CleanupLeftovers(ctx context.Context, envName string, opts Options) error
+}
+
+// Environment is a cluster created by kuma-smoke found on a platform
+type Environment struct {
+	Name              string    `json:"name"`
+	Platform          string    `json:"platform"`
+	CreatedAt         time.Time `json:"createdAt"`
+	KubernetesVersion string    `json:"kubernetesVersion"`
 }
 
 var supportedClusterProviders = map[string]ClusterProvider{}
@@ -20,18 +74,42 @@ func Register(name string, provider ClusterProvider) {
 	SupportedProviderNames = append(SupportedProviderNames, name)
 }
 
-func GetBuilder(providerName string, cmd *cobra.Command, envName string) (clusters.Builder, error) {
+func GetProvider(providerName string) (ClusterProvider, error) {
 	if provider, ok := supportedClusterProviders[providerName]; ok {
-		return provider.ClusterProvider(cmd, envName)
+		return provider, nil
 	}
 
 	return nil, fmt.Errorf("environment platform not supported: %s", providerName)
 }
 
-func NewClusterFromExisting(providerName string, ctx context.Context, cmd *cobra.Command, envName string) (clusters.Cluster, error) {
-	if provider, ok := supportedClusterProviders[providerName]; ok {
-		return provider.NewFromExisting(ctx, cmd, envName)
+func GetBuilder(ctx context.Context, providerName string, envName string, opts Options) (clusters.Builder, error) {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	return provider.ClusterProvider(ctx, envName, opts)
+}
+
+func NewClusterFromExisting(ctx context.Context, providerName string, envName string, opts Options) (clusters.Cluster, error) {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return nil, err
 	}
+	return provider.NewFromExisting(ctx, envName, opts)
+}
 
-	return nil, fmt.Errorf("environment platform not supported: %s", providerName)
+func ListEnvironments(ctx context.Context, providerName string, opts Options) ([]Environment, error) {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	envs, err := provider.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range envs {
+		envs[i].Platform = providerName
+	}
+	return envs, nil
 }
@@ -0,0 +1,68 @@
+package cluster_providers
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"time"
+)
+
+// EnvironmentState records a Kubernetes environment created by kuma-smoke,
+// so that it can still be found and cleaned up when its kubeconfig is lost
+type EnvironmentState struct {
+	Provider          string    `json:"provider"`
+	Name              string    `json:"name"`
+	KubernetesVersion string    `json:"kubernetesVersion"`
+	CreatedAt         time.Time `json:"createdAt"`
+	// Region is where the clusters are located when it is not the default region of the provider
+	Region string `json:"region,omitempty"`
+	// Zones is the number of zone clusters of a multizone environment, it is zero for the other environments
+	Zones int `json:"zones,omitempty"`
+	// Resources are the IDs of the provider-specific resources created along with the cluster,
+	// e.g. the VPC, the IAM roles and the launch template of an EKS cluster
+	Resources map[string]string `json:"resources,omitempty"`
+}
+
+// keys of the resources recorded for the clusters that kuma-smoke did not create, they locate the cluster again
+const (
+	ResourceKubeconfig  = "kubeconfig"
+	ResourceKubeContext = "kubeContext"
+)
+
+// ResourceReporter is implemented by the clusters that own provider-specific resources outside the cluster
+type ResourceReporter interface {
+	Resources() map[string]string
+}
+
+// ClusterOptions returns the options locating the clusters of the environment
+func (s *EnvironmentState) ClusterOptions() Options {
+	return Options{
+		Region:      s.Region,
+		Kubeconfig:  s.Resources[ResourceKubeconfig],
+		KubeContext: s.Resources[ResourceKubeContext],
+	}
+}
+
+func (s *EnvironmentState) Write(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o600)
+}
+
+func LoadEnvironmentState(path string) (*EnvironmentState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read state file %s", path)
+	}
+
+	state := &EnvironmentState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse state file %s", path)
+	}
+	if state.Provider == "" || state.Name == "" {
+		return nil, errors.Errorf("state file %s does not record the provider and the name of an environment", path)
+	}
+	return state, nil
+}
@@ -0,0 +1,82 @@
+package cluster_providers
+
+import (
+	"context"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"golang.org/x/oauth2"
+	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"path/filepath"
+)
+
+// TokenProvider is implemented by the providers whose clusters authenticate with short-lived tokens, e.g. the STS
+// tokens of EKS. Rather than embedding a token, the kubeconfig of such a cluster runs "kuma-smoke kubernetes token"
+// to get a new token whenever the previous one expires.
+type TokenProvider interface {
+	// Token generates a token to access the cluster named envName with
+	Token(ctx context.Context, envName string, opts Options) (*oauth2.Token, error)
+}
+
+// EnvKumaSmokeBin overrides the kuma-smoke binary that the kubeconfigs run to get new tokens, e.g. when the suites
+// are run by ginkgo and kuma-smoke is not in the PATH
+const EnvKumaSmokeBin = "KUMA_SMOKE_BIN"
+
+// RegionReporter is implemented by the clusters located in a region, which may be resolved from the environment
+// variables of the provider rather than given by the options
+type RegionReporter interface {
+	Region() string
+}
+
+// ClusterRegion returns the region of a cluster, the region of the options is preferred over the reported one
+func ClusterRegion(cluster clusters.Cluster, opts Options) string {
+	if opts.Region != "" {
+		return opts.Region
+	}
+	if reporter, ok := cluster.(RegionReporter); ok {
+		return reporter.Region()
+	}
+	return ""
+}
+
+// KubeconfigRestConfig returns the config of a cluster to write into a kubeconfig. For the providers of short-lived
+// tokens, the credentials of the config are replaced by an exec entry running "kuma-smoke kubernetes token" in the
+// region of the cluster.
+func KubeconfigRestConfig(providerName, envName string, cluster clusters.Cluster, opts Options) *rest.Config {
+	config := cluster.Config()
+	if _, ok := supportedClusterProviders[providerName].(TokenProvider); !ok {
+		return config
+	}
+
+	args := []string{"kubernetes", "token", "--env", envName, "--env-platform", providerName}
+	if region := ClusterRegion(cluster, opts); region != "" {
+		args = append(args, "--region", region)
+	}
+
+	exported := rest.CopyConfig(config)
+	exported.BearerToken = ""
+	exported.ExecProvider = &clientcmdapi.ExecConfig{
+		APIVersion:      clientauthenticationv1beta1.SchemeGroupVersion.String(),
+		Command:         kumaSmokeCommand(),
+		Args:            args,
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+	return exported
+}
+
+// kumaSmokeCommand returns the absolute path of kuma-smoke, it is the binary set by KUMA_SMOKE_BIN, e.g. when the
+// suites are run by ginkgo, or the running binary when it is kuma-smoke, e.g. when the suites are run by
+// "kuma-smoke kubernetes run". Otherwise kuma-smoke is looked up in the PATH.
+func kumaSmokeCommand() string {
+	if bin := os.Getenv(EnvKumaSmokeBin); bin != "" {
+		if abs, err := filepath.Abs(bin); err == nil {
+			return abs
+		}
+		return bin
+	}
+	if executable, err := os.Executable(); err == nil && filepath.Base(executable) == "kuma-smoke" {
+		return executable
+	}
+	return "kuma-smoke"
+}
@@ -0,0 +1,245 @@
+package product
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Fetcher gets kumactl of product releases, either by downloading the release archives or from a local directory
+type Fetcher struct {
+	// CacheDir is where kumactl is unpacked into, using the same layout as the installer: <CacheDir>/<product>-<version>/bin/kumactl
+	CacheDir string
+	// ArchiveDir is a local directory holding release archives (and optionally their .sha256 files).
+	// When it is set, nothing is downloaded, so that the fetcher can be used in offline runs.
+	ArchiveDir string
+	// Client is used to download the release archives, http.DefaultClient is used when it is nil
+	Client *http.Client
+	// Warnf reports the problems that do not fail fetching, e.g. a release archive that can't be verified
+	Warnf func(format string, args ...interface{})
+}
+
+// errNotFound is returned when a downloaded file does not exist
+var errNotFound = errors.New("not found")
+
+// KumactlPath returns the path kumactl of the release is unpacked into
+func (f *Fetcher) KumactlPath(r Release) string {
+	return filepath.Join(f.CacheDir, r.Name(), "bin", "kumactl")
+}
+
+// Fetch makes kumactl of the release available in the cache directory and returns its path.
+// Releases that are already in the cache are not fetched again.
+func (f *Fetcher) Fetch(ctx context.Context, r Release) (string, error) {
+	kumactlPath := f.KumactlPath(r)
+	if _, err := os.Stat(kumactlPath); err == nil {
+		return kumactlPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(kumactlPath), 0o755); err != nil {
+		return "", err
+	}
+
+	var archivePath string
+	var err error
+	if f.ArchiveDir != "" {
+		archivePath, err = f.localArchive(r)
+	} else {
+		archivePath, err = f.download(ctx, r)
+		if archivePath != "" {
+			defer os.Remove(archivePath)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := extractKumactl(archivePath, r, kumactlPath); err != nil {
+		return "", errors.Wrapf(err, "failed to unpack kumactl from %s", archivePath)
+	}
+	return kumactlPath, nil
+}
+
+// localArchive returns the path of the release archive in the archive directory,
+// the archive is verified when its checksum file is found next to it
+func (f *Fetcher) localArchive(r Release) (string, error) {
+	archivePath := filepath.Join(f.ArchiveDir, r.ArchiveName())
+	if _, err := os.Stat(archivePath); err != nil {
+		return "", errors.Wrapf(err, "release archive of %s not found in %s", r.Name(), f.ArchiveDir)
+	}
+
+	checksum, err := os.ReadFile(filepath.Join(f.ArchiveDir, r.ChecksumName()))
+	if os.IsNotExist(err) {
+		f.warnf("no checksum of %s is found in %s, the release archive is not verified\n", r.ArchiveName(), f.ArchiveDir)
+		return archivePath, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	expected, err := parseChecksum(string(checksum))
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid checksum file of %s", r.ArchiveName())
+	}
+	actual, err := fileChecksum(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if actual != expected {
+		return "", errors.Errorf("checksum mismatch of %s: expected %s, got %s", archivePath, expected, actual)
+	}
+	return archivePath, nil
+}
+
+// download downloads the release archive into a temporary file of the cache directory and verifies its checksum.
+// The archive is not verified when no checksum is published for it, as the installers never verify it either.
+func (f *Fetcher) download(ctx context.Context, r Release) (string, error) {
+	var expected string
+	var checksum strings.Builder
+	err := f.get(ctx, r.ChecksumURL(), &checksum)
+	switch {
+	case errors.Is(err, errNotFound):
+		f.warnf("no checksum is published for %s, the release archive is not verified\n", r.ArchiveName())
+	case err != nil:
+		return "", errors.Wrapf(err, "failed to download checksum of %s", r.Name())
+	default:
+		expected, err = parseChecksum(checksum.String())
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid checksum of %s", r.ArchiveName())
+		}
+	}
+
+	archive, err := os.CreateTemp(f.CacheDir, r.ArchiveName())
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	hash := sha256.New()
+	if err := f.get(ctx, r.ArchiveURL(), io.MultiWriter(archive, hash)); err != nil {
+		return archive.Name(), errors.Wrapf(err, "failed to download release archive of %s", r.Name())
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); expected != "" && actual != expected {
+		return archive.Name(), errors.Errorf("checksum mismatch of %s: expected %s, got %s", r.ArchiveURL(), expected, actual)
+	}
+	return archive.Name(), nil
+}
+
+func (f *Fetcher) get(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.Wrapf(errNotFound, "%s", url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (f *Fetcher) warnf(format string, args ...interface{}) {
+	if f.Warnf != nil {
+		f.Warnf(format, args...)
+	}
+}
+
+// parseChecksum reads a checksum in the format of sha256sum, e.g. "<hex>  kuma-2.9.2-linux-amd64.tar.gz"
+func parseChecksum(content string) (string, error) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return "", errors.New("checksum is empty")
+	}
+
+	checksum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return "", errors.Errorf("%q is not a SHA-256 checksum", fields[0])
+	}
+	return checksum, nil
+}
+
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractKumactl unpacks <release name>/bin/kumactl from the archive into target.
+// It is written into a temporary file first, so that an interrupted fetch never leaves a broken kumactl in the cache.
+func extractKumactl(archivePath string, r Release, target string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	entryName := path.Join(r.Name(), "bin", "kumactl")
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return errors.Errorf("%s not found in the archive", entryName)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || path.Clean(header.Name) != entryName {
+			continue
+		}
+
+		tmp, err := os.CreateTemp(filepath.Dir(target), "kumactl")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tmp, tr)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0o755)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), target)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+			return errors.Wrapf(err, "failed to write %s", target)
+		}
+		return nil
+	}
+}
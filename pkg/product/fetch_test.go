@@ -0,0 +1,223 @@
+package product
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// newArchive builds a release archive holding the files, keyed by their paths inside the archive
+func newArchive(files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		Expect(tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o755,
+			Size:     int64(len(content)),
+		})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func checksumOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// redirectTransport sends all the requests to the test server, whatever host they are made to
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+var _ = Describe("fetching kumactl", func() {
+	release := Release{Product: "kuma", Version: "2.9.2", OS: "linux", Arch: "amd64"}
+	kumactlEntry := "kuma-2.9.2/bin/kumactl"
+
+	var cacheDir string
+	var warnings []string
+	var fetcher *Fetcher
+
+	BeforeEach(func() {
+		cacheDir = GinkgoT().TempDir()
+		warnings = nil
+		fetcher = &Fetcher{
+			CacheDir: cacheDir,
+			Warnf: func(format string, args ...interface{}) {
+				warnings = append(warnings, fmt.Sprintf(format, args...))
+			},
+		}
+	})
+
+	Context("from the published releases", func() {
+		var files map[string][]byte
+		var requests atomic.Int32
+
+		BeforeEach(func() {
+			files = map[string][]byte{}
+			requests.Store(0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requests.Add(1)
+				content, ok := files[req.URL.Path]
+				if !ok {
+					http.NotFound(w, req)
+					return
+				}
+				_, _ = w.Write(content)
+			}))
+			DeferCleanup(server.Close)
+
+			target, err := url.Parse(server.URL)
+			Expect(err).ToNot(HaveOccurred())
+			fetcher.Client = &http.Client{Transport: redirectTransport{target: target}}
+		})
+
+		publish := func(archive []byte, checksum string) {
+			archiveURL, err := url.Parse(release.ArchiveURL())
+			Expect(err).ToNot(HaveOccurred())
+			files[archiveURL.Path] = archive
+			if checksum != "" {
+				files[archiveURL.Path+".sha256"] = []byte(checksum + "  " + release.ArchiveName() + "\n")
+			}
+		}
+
+		It("should unpack kumactl of a verified archive", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			publish(archive, checksumOf(archive))
+
+			kumactlPath, err := fetcher.Fetch(ctx, release)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kumactlPath).To(Equal(filepath.Join(cacheDir, "kuma-2.9.2", "bin", "kumactl")))
+			Expect(os.ReadFile(kumactlPath)).To(Equal([]byte("kumactl binary")))
+			Expect(warnings).To(BeEmpty())
+
+			info, err := os.Stat(kumactlPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o755)))
+		})
+
+		It("should fail on a checksum mismatch", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			publish(archive, checksumOf([]byte("another archive")))
+
+			_, err := fetcher.Fetch(ctx, release)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+			Expect(fetcher.KumactlPath(release)).ToNot(BeAnExistingFile())
+			Expect(filepath.Glob(filepath.Join(cacheDir, release.ArchiveName()+"*"))).To(BeEmpty())
+		})
+
+		It("should fall back to an unverified archive with a warning when no checksum is published", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			publish(archive, "")
+
+			kumactlPath, err := fetcher.Fetch(ctx, release)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.ReadFile(kumactlPath)).To(Equal([]byte("kumactl binary")))
+			Expect(warnings).To(ConsistOf(ContainSubstring("not verified")))
+		})
+
+		It("should fail when the archive is not published", func(ctx SpecContext) {
+			_, err := fetcher.Fetch(ctx, release)
+			Expect(err).To(MatchError(ContainSubstring("failed to download release archive")))
+		})
+
+		It("should fail when kumactl is not in the archive", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{"kuma-2.9.2/bin/kuma-cp": "kuma-cp binary"})
+			publish(archive, checksumOf(archive))
+
+			_, err := fetcher.Fetch(ctx, release)
+			Expect(err).To(MatchError(ContainSubstring(kumactlEntry + " not found in the archive")))
+			Expect(fetcher.KumactlPath(release)).ToNot(BeAnExistingFile())
+		})
+
+		It("should not download a release that is already in the cache", func(ctx SpecContext) {
+			kumactlPath := fetcher.KumactlPath(release)
+			Expect(os.MkdirAll(filepath.Dir(kumactlPath), 0o755)).To(Succeed())
+			Expect(os.WriteFile(kumactlPath, []byte("cached kumactl"), 0o755)).To(Succeed())
+
+			Expect(fetcher.Fetch(ctx, release)).To(Equal(kumactlPath))
+			Expect(os.ReadFile(kumactlPath)).To(Equal([]byte("cached kumactl")))
+			Expect(requests.Load()).To(BeZero())
+		})
+	})
+
+	Context("from a local archive directory", func() {
+		BeforeEach(func() {
+			fetcher.ArchiveDir = GinkgoT().TempDir()
+		})
+
+		It("should unpack kumactl of a verified archive", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			Expect(os.WriteFile(filepath.Join(fetcher.ArchiveDir, release.ArchiveName()), archive, 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(fetcher.ArchiveDir, release.ChecksumName()), []byte(checksumOf(archive)), 0o644)).To(Succeed())
+
+			kumactlPath, err := fetcher.Fetch(ctx, release)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.ReadFile(kumactlPath)).To(Equal([]byte("kumactl binary")))
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("should fail on a checksum mismatch", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			Expect(os.WriteFile(filepath.Join(fetcher.ArchiveDir, release.ArchiveName()), archive, 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(fetcher.ArchiveDir, release.ChecksumName()), []byte(checksumOf([]byte("another archive"))), 0o644)).To(Succeed())
+
+			_, err := fetcher.Fetch(ctx, release)
+			Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+			Expect(fetcher.KumactlPath(release)).ToNot(BeAnExistingFile())
+		})
+
+		It("should unpack kumactl of an unverified archive with a warning when its checksum file is missing", func(ctx SpecContext) {
+			archive := newArchive(map[string]string{kumactlEntry: "kumactl binary"})
+			Expect(os.WriteFile(filepath.Join(fetcher.ArchiveDir, release.ArchiveName()), archive, 0o644)).To(Succeed())
+
+			kumactlPath, err := fetcher.Fetch(ctx, release)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.ReadFile(kumactlPath)).To(Equal([]byte("kumactl binary")))
+			Expect(warnings).To(ConsistOf(ContainSubstring("not verified")))
+		})
+
+		It("should fail when the archive is missing", func(ctx SpecContext) {
+			_, err := fetcher.Fetch(ctx, release)
+			Expect(err).To(MatchError(ContainSubstring("release archive of kuma-2.9.2 not found")))
+		})
+	})
+})
+
+var _ = Describe("parsing checksums", func() {
+	It("should accept the format of sha256sum", func() {
+		checksum := checksumOf([]byte("archive"))
+		Expect(parseChecksum(checksum + "  kuma-2.9.2-linux-amd64.tar.gz\n")).To(Equal(checksum))
+	})
+
+	It("should reject content that is not a SHA-256 checksum", func() {
+		_, err := parseChecksum("not-a-checksum  kuma-2.9.2-linux-amd64.tar.gz")
+		Expect(err).To(HaveOccurred())
+		_, err = parseChecksum("")
+		Expect(err).To(HaveOccurred())
+	})
+})
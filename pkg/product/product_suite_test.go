@@ -0,0 +1,12 @@
+package product
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestProduct(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Product Suite")
+}
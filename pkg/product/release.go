@@ -0,0 +1,55 @@
+package product
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// binariesBaseURL is where the release archives installed by https://kuma.io/installer.sh and
+// https://docs.konghq.com/mesh/installer.sh are published
+const binariesBaseURL = "https://packages.konghq.com/public"
+
+// Release identifies the release archive of a product version built for a platform
+type Release struct {
+	Product string
+	Version string
+	OS      string
+	Arch    string
+}
+
+// NewRelease returns the release of the product version built for the current platform
+func NewRelease(product, version string) Release {
+	return Release{
+		Product: product,
+		Version: strings.TrimPrefix(version, "v"),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+	}
+}
+
+// Name returns the name of the release, it is also the top level directory inside the archive, e.g. "kuma-2.9.2"
+func (r Release) Name() string {
+	return fmt.Sprintf("%s-%s", r.Product, r.Version)
+}
+
+// ArchiveName returns the file name of the release archive, e.g. "kuma-2.9.2-linux-amd64.tar.gz"
+func (r Release) ArchiveName() string {
+	return fmt.Sprintf("%s-%s-%s.tar.gz", r.Name(), r.OS, r.Arch)
+}
+
+// ChecksumName returns the file name of the SHA-256 checksum published along with the release archive
+func (r Release) ChecksumName() string {
+	return r.ArchiveName() + ".sha256"
+}
+
+// ArchiveURL returns the URL the release archive is downloaded from
+func (r Release) ArchiveURL() string {
+	return fmt.Sprintf("%s/%s-binaries-release/raw/names/%s-%s-%s/versions/%s/%s",
+		binariesBaseURL, r.Product, r.Product, r.OS, r.Arch, r.Version, r.ArchiveName())
+}
+
+// ChecksumURL returns the URL the checksum of the release archive is downloaded from
+func (r Release) ChecksumURL() string {
+	return r.ArchiveURL() + ".sha256"
+}
@@ -0,0 +1,153 @@
+package universal
+
+import (
+	"context"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	"github.com/pkg/errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// DockerNetwork is the network that the Kuma test framework attaches Universal containers to
+	DockerNetwork = "kind"
+	// ImageRepo is the name of the image that contains kuma-cp, kuma-dp and their dependencies
+	ImageRepo = "kuma-universal"
+
+	cpAppName = "kuma-cp"
+	cpLogFile = "/tmp/kuma-cp.log"
+)
+
+type DeployOptions struct {
+	// Name is the name of the environment, all the containers are prefixed with it
+	Name string
+	// Image is the full reference of the kuma-universal image used to run the control plane
+	Image string
+	// EnvVars are extra environment variables passed to kuma-cp
+	EnvVars map[string]string
+}
+
+// ImageRef returns the reference of the kuma-universal image of a product version
+func ImageRef(registry, version string) string {
+	return fmt.Sprintf("%s/%s:%s", registry, ImageRepo, version)
+}
+
+// Deploy starts a zone control plane in a container of the docker network used by the Kuma test framework.
+// kuma-cp is started detached from this process, so the control plane keeps running after it returns.
+func Deploy(ctx context.Context, opts DeployOptions) (*State, error) {
+	if err := ensureNetwork(ctx, DockerNetwork); err != nil {
+		return nil, errors.Wrapf(err, "failed to prepare docker network %s", DockerNetwork)
+	}
+
+	container := fmt.Sprintf("%s_%s", opts.Name, cpAppName)
+	_, err := runDocker(ctx, "run", "--detach", "--rm",
+		"--name", container,
+		"--network", DockerNetwork,
+		"--publish", "0.0.0.0::22",
+		"--publish", "0.0.0.0::5681",
+		"--sysctl", "net.ipv6.conf.all.disable_ipv6=1",
+		opts.Image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to start control plane container %s", container)
+	}
+
+	state, err := startControlPlane(ctx, container, opts)
+	if err != nil {
+		// kuma-cp runs detached and the caller gets no state on failures, so the container would otherwise leak
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), utils.CleanupTimeout)
+		defer cancel()
+		if cleanupErr := Cleanup(cleanupCtx, opts.Name); cleanupErr != nil {
+			return nil, errors.Errorf("%v (the containers of environment %s could not be removed: %v)", err, opts.Name, cleanupErr)
+		}
+		return nil, err
+	}
+	return state, nil
+}
+
+func startControlPlane(ctx context.Context, container string, opts DeployOptions) (*State, error) {
+	execArgs := []string{"exec", "--detach"}
+	envVars := map[string]string{
+		"KUMA_MODE":            "zone",
+		"KUMA_DNS_SERVER_PORT": "53",
+	}
+	for k, v := range opts.EnvVars {
+		envVars[k] = v
+	}
+	for k, v := range envVars {
+		execArgs = append(execArgs, "--env", fmt.Sprintf("%s=%s", k, v))
+	}
+	execArgs = append(execArgs, container, "sh", "-c",
+		fmt.Sprintf("kuma-cp run --config-file /kuma/kuma-cp.conf > %s 2>&1", cpLogFile))
+	if _, err := runDocker(ctx, execArgs...); err != nil {
+		return nil, errors.Wrapf(err, "failed to start kuma-cp in container %s", container)
+	}
+
+	ip, err := containerIP(ctx, container, DockerNetwork)
+	if err != nil {
+		return nil, err
+	}
+	apiServerPort, err := publishedPort(ctx, container, "5681")
+	if err != nil {
+		return nil, err
+	}
+	sshPort, err := publishedPort(ctx, container, "22")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := waitForAPIServer(ctx, apiServerPort); err != nil {
+		return nil, errors.Wrapf(err, "failed while waiting for the control plane in container %s to become ready", container)
+	}
+
+	return &State{
+		Name:    opts.Name,
+		Image:   opts.Image,
+		Network: DockerNetwork,
+		KumaCp: Networking{
+			IP:            ip,
+			ApiServerPort: apiServerPort,
+			SshPort:       sshPort,
+		},
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Cleanup removes the control plane and all the dataplane containers created for the environment
+func Cleanup(ctx context.Context, envName string) error {
+	return removeContainers(ctx, envName+"_")
+}
+
+// ControlPlaneLogs returns the logs written by kuma-cp in the environment
+func ControlPlaneLogs(ctx context.Context, envName string) (string, error) {
+	return runDocker(ctx, "exec", fmt.Sprintf("%s_%s", envName, cpAppName), "cat", cpLogFile)
+}
+
+func waitForAPIServer(ctx context.Context, apiServerPort string) error {
+	childCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	url := fmt.Sprintf("http://localhost:%s/", apiServerPort)
+	for {
+		select {
+		case <-childCtx.Done():
+			return childCtx.Err()
+		case <-ticker.C:
+			req, err := http.NewRequestWithContext(childCtx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				// the API server is not listening yet
+				continue
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+	}
+}
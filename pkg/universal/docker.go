@@ -0,0 +1,94 @@
+package universal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+func runDocker(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run 'docker %s': %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func ensureNetwork(ctx context.Context, network string) error {
+	if _, err := runDocker(ctx, "network", "inspect", network); err == nil {
+		return nil
+	}
+
+	_, err := runDocker(ctx, "network", "create", network)
+	return err
+}
+
+func containerIP(ctx context.Context, container, network string) (string, error) {
+	ip, err := runDocker(ctx, "inspect", "--format",
+		fmt.Sprintf(`{{ (index .NetworkSettings.Networks %q).IPAddress }}`, network), container)
+	if err != nil {
+		return "", err
+	}
+	if ip == "" {
+		return "", errors.Errorf("container %s has no IP address in network %s", container, network)
+	}
+	return ip, nil
+}
+
+func publishedPort(ctx context.Context, container, port string) (string, error) {
+	out, err := runDocker(ctx, "port", container, port)
+	if err != nil {
+		return "", err
+	}
+
+	hostPort, err := parsePublishedPort(out)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse published address of port %s on container %s", port, container)
+	}
+	return hostPort, nil
+}
+
+// parsePublishedPort returns the host port from the output of 'docker port',
+// which may contain one line per address family, e.g. "0.0.0.0:32768" and ":::32768"
+func parsePublishedPort(out string) (string, error) {
+	addr := strings.TrimSpace(strings.Split(out, "\n")[0])
+	if strings.HasPrefix(addr, ":::") {
+		addr = "[::]:" + addr[3:]
+	}
+	_, hostPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if hostPort == "" {
+		return "", errors.Errorf("no host port in address %q", addr)
+	}
+	return hostPort, nil
+}
+
+func removeContainers(ctx context.Context, namePrefix string) error {
+	// the name filter of docker matches substrings, so only containers starting with the prefix are kept
+	out, err := runDocker(ctx, "ps", "--all", "--filter", "name="+namePrefix, "--format", "{{ .Names }}")
+	if err != nil {
+		return err
+	}
+
+	var containers []string
+	for _, name := range strings.Fields(out) {
+		if strings.HasPrefix(name, namePrefix) {
+			containers = append(containers, name)
+		}
+	}
+	if len(containers) == 0 {
+		return nil
+	}
+
+	_, err = runDocker(ctx, append([]string{"rm", "--force"}, containers...)...)
+	return err
+}
@@ -0,0 +1,29 @@
+package universal
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsing published ports", func() {
+	DescribeTable("should return the host port",
+		func(out, expected string) {
+			Expect(parsePublishedPort(out)).To(Equal(expected))
+		},
+		Entry("IPv4 address", "0.0.0.0:32768", "32768"),
+		Entry("IPv6 address", ":::32769", "32769"),
+		Entry("bracketed IPv6 address", "[::]:32770", "32770"),
+		Entry("one line per address family", "0.0.0.0:32771\n:::32771", "32771"),
+		Entry("trailing whitespace", "0.0.0.0:32772\n", "32772"),
+	)
+
+	DescribeTable("should fail on unexpected output",
+		func(out string) {
+			_, err := parsePublishedPort(out)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty output", ""),
+		Entry("no port", "0.0.0.0"),
+		Entry("empty port", "0.0.0.0:"),
+	)
+})
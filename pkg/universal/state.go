@@ -0,0 +1,47 @@
+package universal
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"time"
+)
+
+// Networking describes how a container can be reached from the host,
+// it shares the JSON layout of UniversalNetworking from the Kuma test framework
+type Networking struct {
+	IP            string `json:"ip"`
+	ApiServerPort string `json:"apiServerPort"`
+	SshPort       string `json:"sshPort"`
+}
+
+// State records a Universal environment created by Deploy,
+// so that the test suite can connect to it and cleanup can find its containers
+type State struct {
+	Name      string     `json:"name"`
+	Image     string     `json:"image"`
+	Network   string     `json:"network"`
+	KumaCp    Networking `json:"kumaCp"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (s *State) Write(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o600)
+}
+
+func LoadState(path string) (*State, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read state file %s", path)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse state file %s", path)
+	}
+	return state, nil
+}
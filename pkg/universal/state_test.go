@@ -0,0 +1,46 @@
+package universal
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("environment state", func() {
+	It("should be loaded as it was written", func() {
+		state := &State{
+			Name:    "smoke-abcde",
+			Image:   "kumahq/kuma-universal:2.9.2",
+			Network: DockerNetwork,
+			KumaCp: Networking{
+				IP:            "172.18.0.2",
+				ApiServerPort: "32768",
+				SshPort:       "32769",
+			},
+			CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		path := filepath.Join(GinkgoT().TempDir(), "state.json")
+		Expect(state.Write(path)).To(Succeed())
+
+		Expect(LoadState(path)).To(Equal(state))
+	})
+
+	It("should share the networking layout of the Kuma test framework", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "state.json")
+		Expect(os.WriteFile(path, []byte(`{"name":"smoke-abcde","kumaCp":{"ip":"172.18.0.2","apiServerPort":"32768","sshPort":"32769"}}`), 0o600)).To(Succeed())
+
+		state, err := LoadState(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.KumaCp).To(Equal(Networking{IP: "172.18.0.2", ApiServerPort: "32768", SshPort: "32769"}))
+	})
+
+	It("should fail on a malformed state file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "state.json")
+		Expect(os.WriteFile(path, []byte("{"), 0o600)).To(Succeed())
+
+		_, err := LoadState(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
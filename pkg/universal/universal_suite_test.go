@@ -0,0 +1,12 @@
+package universal
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestUniversal(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Universal Suite")
+}
@@ -2,6 +2,13 @@ package utils
 
 import "time"
 
+const (
+	// EnvNamePrefix is the prefix of the names of all the environments created by kuma-smoke
+	EnvNamePrefix = "kuma-smoke-"
+	// MultizoneGlobalSuffix is appended to the name of a multizone environment to name its global cluster
+	MultizoneGlobalSuffix = "global"
+)
+
 var (
 	EnvironmentCreateTimeout = time.Minute * 30
 	CleanupTimeout           = time.Minute * 20
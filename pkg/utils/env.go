@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// NewEnvName generates a random environment name in the form of "kuma-smoke-XXXXXXXXXX"
+func NewEnvName() string {
+	randomName := strings.Replace(uuid.NewString(), "-", "", -1)
+	return EnvNamePrefix + randomName[len(randomName)-10:]
+}
+
+// MultizoneClusterNames returns the names of the clusters of a multizone environment, the global cluster comes first
+func MultizoneClusterNames(envName string, zones int) []string {
+	names := []string{envName + "-" + MultizoneGlobalSuffix}
+	for i := 1; i <= zones; i++ {
+		names = append(names, fmt.Sprintf("%s-zone-%d", envName, i))
+	}
+	return names
+}
@@ -6,15 +6,24 @@ import (
 	"io"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-func writeKubeconfigToFile(envName string, config *rest.Config, filename string) error {
-	kubeconfig := generators.NewClientConfigForRestConfig(envName, config)
+// WriteKubeconfigToFile writes the kubeconfig of a cluster into filename
+func WriteKubeconfigToFile(envName string, config *rest.Config, filename string) error {
+	kubeconfig := newKubeconfig(envName, config)
 	return clientcmd.WriteToFile(*kubeconfig, filename)
 }
 
-func writeKubeconfigToOutput(envName string, config *rest.Config, writer io.Writer) error {
+// newKubeconfig generates the kubeconfig of a cluster, including the exec entry of the config if any
+func newKubeconfig(envName string, config *rest.Config) *clientcmdapi.Config {
 	kubeconfig := generators.NewClientConfigForRestConfig(envName, config)
+	kubeconfig.AuthInfos[envName].Exec = config.ExecProvider
+	return kubeconfig
+}
+
+func writeKubeconfigToOutput(envName string, config *rest.Config, writer io.Writer) error {
+	kubeconfig := newKubeconfig(envName, config)
 	content, err := clientcmd.Write(*kubeconfig)
 	if err != nil {
 		return err
@@ -28,6 +37,6 @@ func WriteKubeconfig(envName string, cmd *cobra.Command, config *rest.Config, ou
 	if output == "-" {
 		return writeKubeconfigToOutput(envName, config, cmd.OutOrStdout())
 	} else {
-		return writeKubeconfigToFile(envName, config, output)
+		return WriteKubeconfigToFile(envName, config, output)
 	}
 }
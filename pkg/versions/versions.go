@@ -0,0 +1,121 @@
+package versions
+
+import (
+	"bufio"
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+)
+
+// UpgradeFrom is the set of versions that the upgrade tests of a target version start from
+type UpgradeFrom struct {
+	Target semver.Version
+	// PrevMinor is a release of the minor version before the target, it may belong to the previous major version
+	PrevMinor semver.Version
+	// PrevPatch is the patch release before the target within the same minor version,
+	// it is nil when the target is the first release of its minor version
+	PrevPatch *semver.Version
+}
+
+// Parse parses a product version, the "v" prefix is optional
+func Parse(version string) (semver.Version, error) {
+	v, err := semver.Parse(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+	if err != nil {
+		return semver.Version{}, errors.Wrapf(err, "invalid version %q", version)
+	}
+	return v, nil
+}
+
+// Resolve computes the versions to upgrade to the target from.
+//
+// Without known releases, the previous minor is the first patch of the previous minor version and the previous patch
+// is the patch version minus one. The previous minor of a major release (e.g. 3.0.0) can't be computed in this way,
+// so known releases are required for it.
+//
+// With known releases, the latest known release of each kind is returned, which also works across major versions and
+// skips patch versions that were never released. Pre-releases in the known releases are ignored.
+//
+// A pre-release target (e.g. 2.10.0-preview.abc) is treated as the release it precedes. A development build
+// (0.0.0-preview.abc) is treated as a release after all the known releases, so known releases are required for it.
+func Resolve(target string, knownReleases []semver.Version) (UpgradeFrom, error) {
+	targetVer, err := Parse(target)
+	if err != nil {
+		return UpgradeFrom{}, err
+	}
+	result := UpgradeFrom{Target: targetVer}
+	base := semver.Version{Major: targetVer.Major, Minor: targetVer.Minor, Patch: targetVer.Patch}
+
+	var released []semver.Version
+	for _, v := range knownReleases {
+		if len(v.Pre) == 0 {
+			released = append(released, v)
+		}
+	}
+	semver.Sort(released)
+
+	if base.EQ(semver.Version{}) {
+		if len(released) == 0 {
+			return UpgradeFrom{}, errors.Errorf("known releases are required to resolve the versions to upgrade development build %s from", target)
+		}
+		result.PrevMinor = released[len(released)-1]
+		return result, nil
+	}
+
+	if len(released) == 0 {
+		if base.Minor == 0 {
+			return UpgradeFrom{}, errors.Errorf("known releases are required to resolve the previous minor version of %s", target)
+		}
+		result.PrevMinor = semver.Version{Major: base.Major, Minor: base.Minor - 1}
+		if base.Patch > 0 {
+			result.PrevPatch = &semver.Version{Major: base.Major, Minor: base.Minor, Patch: base.Patch - 1}
+		}
+		return result, nil
+	}
+
+	// released is sorted, so the first match from the end is the latest release
+	found := false
+	for i := len(released) - 1; i >= 0; i-- {
+		v := released[i]
+		if !found && (v.Major < base.Major || (v.Major == base.Major && v.Minor < base.Minor)) {
+			result.PrevMinor = v
+			found = true
+		}
+		if result.PrevPatch == nil && v.Major == base.Major && v.Minor == base.Minor && v.LT(base) {
+			result.PrevPatch = &released[i]
+		}
+	}
+	if !found {
+		return UpgradeFrom{}, errors.Errorf("no known release of a minor version before %s", target)
+	}
+	return result, nil
+}
+
+// LoadKnownReleases parses the versions of known releases, the versions listed in the file are added when file is not empty.
+// The file has one version per line, empty lines and lines starting with "#" are ignored.
+func LoadKnownReleases(versions []string, file string) ([]semver.Version, error) {
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read known releases file %s", file)
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				versions = append(versions, line)
+			}
+		}
+	}
+
+	var releases []semver.Version
+	for _, version := range versions {
+		v, err := Parse(version)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, v)
+	}
+	return releases, nil
+}
@@ -0,0 +1,93 @@
+package versions
+
+import (
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("resolving the versions to upgrade from", func() {
+	knownReleases := []string{
+		"2.8.0", "2.8.1", "2.8.2",
+		"2.9.0", "2.9.1", "2.9.3", // 2.9.2 was never released
+		"2.10.0-preview.1",
+		"3.0.0-rc.1",
+	}
+
+	DescribeTable("should resolve the previous minor and patch versions",
+		func(target string, known []string, prevMinor, prevPatch string) {
+			releases, err := LoadKnownReleases(known, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			upgradeFrom, err := Resolve(target, releases)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(upgradeFrom.PrevMinor.String()).To(Equal(prevMinor))
+			if prevPatch == "" {
+				Expect(upgradeFrom.PrevPatch).To(BeNil())
+			} else {
+				Expect(upgradeFrom.PrevPatch).ToNot(BeNil())
+				Expect(upgradeFrom.PrevPatch.String()).To(Equal(prevPatch))
+			}
+		},
+		Entry("a patch release without known releases", "2.9.2", nil, "2.8.0", "2.9.1"),
+		Entry("the first release of a minor version without known releases", "2.9.0", nil, "2.8.0", ""),
+		Entry("a version with the v prefix", "v2.9.2", nil, "2.8.0", "2.9.1"),
+		Entry("a preview of a minor version without known releases", "2.10.0-preview.abc", nil, "2.9.0", ""),
+		Entry("a preview of a patch version without known releases", "2.9.3-preview.abc", nil, "2.8.0", "2.9.2"),
+		Entry("a patch release with known releases", "2.9.1", knownReleases, "2.8.2", "2.9.0"),
+		Entry("a patch release after a skipped patch", "2.9.3", knownReleases, "2.8.2", "2.9.1"),
+		Entry("the next patch release", "2.9.4", knownReleases, "2.8.2", "2.9.3"),
+		Entry("the first release of a minor version with known releases", "2.10.0", knownReleases, "2.9.3", ""),
+		Entry("a preview of a minor version with known releases", "2.10.0-preview.abc", knownReleases, "2.9.3", ""),
+		Entry("a major release with known releases", "3.0.0", knownReleases, "2.9.3", ""),
+		Entry("a preview of a major release with known releases", "3.0.0-preview.abc", knownReleases, "2.9.3", ""),
+		Entry("a patch of a major release with known releases", "3.0.1", append(knownReleases, "3.0.0"), "2.9.3", "3.0.0"),
+		Entry("a development build", "0.0.0-preview.abc", knownReleases, "2.9.3", ""),
+	)
+
+	DescribeTable("should fail when the versions can't be resolved",
+		func(target string, known []string, errMessage string) {
+			releases, err := LoadKnownReleases(known, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = Resolve(target, releases)
+			Expect(err).To(MatchError(ContainSubstring(errMessage)))
+		},
+		Entry("a major release without known releases", "3.0.0", nil, "known releases are required"),
+		Entry("a development build without known releases", "0.0.0-preview.abc", nil, "known releases are required"),
+		Entry("a development build with only pre-releases known", "0.0.0-preview.abc", []string{"2.10.0-preview.1"}, "known releases are required"),
+		Entry("a version older than all the known releases", "2.8.1", knownReleases, "no known release of a minor version before 2.8.1"),
+		Entry("an invalid version", "2.9", nil, "invalid version"),
+	)
+})
+
+var _ = Describe("loading known releases", func() {
+	It("should ignore comments and blank lines of the file", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "releases")
+		Expect(os.WriteFile(file, []byte("# released versions\n2.9.0\n\n  v2.9.1  \n# 2.9.2 was never released\n\t\n2.9.3\n"), 0o644)).To(Succeed())
+
+		releases, err := LoadKnownReleases([]string{"2.8.2"}, file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(Equal([]semver.Version{
+			semver.MustParse("2.8.2"),
+			semver.MustParse("2.9.0"),
+			semver.MustParse("2.9.1"),
+			semver.MustParse("2.9.3"),
+		}))
+	})
+
+	It("should fail on an invalid version", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "releases")
+		Expect(os.WriteFile(file, []byte("2.9.0\nlatest\n"), 0o644)).To(Succeed())
+
+		_, err := LoadKnownReleases(nil, file)
+		Expect(err).To(MatchError(ContainSubstring(`invalid version "latest"`)))
+	})
+
+	It("should fail on a missing file", func() {
+		_, err := LoadKnownReleases(nil, filepath.Join(GinkgoT().TempDir(), "missing"))
+		Expect(err).To(MatchError(ContainSubstring("failed to read known releases file")))
+	})
+})
@@ -0,0 +1,28 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+)
+
+// ExportKubeConfig writes the kubeconfig of an existing cluster into exportPath, the provider of the cluster must be
+// registered by the caller. The kubeconfig of a cluster authenticating with short-lived tokens refreshes its token
+// by running kuma-smoke, so that it keeps working during long test runs.
+func ExportKubeConfig(envType string, envName string, opts cluster_providers.Options, exportPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), utils.EnvironmentCreateTimeout)
+	defer cancel()
+
+	existingCls, err := cluster_providers.NewClusterFromExisting(ctx, envType, envName, opts)
+	if err != nil {
+		return fmt.Errorf("failed to get existing %s cluster %s: %w", envType, envName, err)
+	}
+
+	kubeconfig := cluster_providers.KubeconfigRestConfig(envType, envName, existingCls, opts)
+	err = utils.WriteKubeconfigToFile(envName, kubeconfig, exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to export kubeconfig for existing %s cluster %s: %w", envType, envName, err)
+	}
+	return nil
+}
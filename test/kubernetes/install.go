@@ -1,13 +1,18 @@
-package kubernetes_test
+package kubernetes
 
 import (
+	"context"
 	"fmt"
 	"github.com/gruntwork-io/terratest/modules/helm"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/kong/kubernetes-testing-framework/pkg/clusters"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
 	"github.com/kumahq/kuma/test/framework/deployments/kic"
 	"github.com/kumahq/kuma/test/framework/kumactl"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -46,7 +51,7 @@ func Install() {
 			}
 
 			err := NewClusterSetup().
-				Install(Kuma(core.Zone, createKumaDeployOptions(installMode, cni, Config.KumaImageTag)...)).
+				Install(Kuma(core.Zone, createKumaDeployOptions(installMode, cni, targetVersion.String())...)).
 				Install(NamespaceWithSidecarInjection(TestNamespace)).
 				Setup(cluster)
 			Expect(err).ToNot(HaveOccurred())
@@ -292,15 +297,18 @@ func requestFromGateway(gwAppName string, requestHost, requestPath string, respo
 			gatewayPodName,
 			"kuma-gateway",
 			"wget", "-q", "-O", "-", "-S", "-T", "3",
-			requestHost+requestPath)
+			urlHost(requestHost)+requestPath)
 		responseChecker(g, stderr+stdout)
 	}, "30s", "1s").Should(Succeed())
 }
 
+// getServiceIP returns the cluster IP of the service in the IP family that the services are requested over.
+// Services are single-stack by default even on dual-stack clusters, so the service is made dual-stack when it has
+// no cluster IP in the IP family.
 func getServiceIP(cluster *K8sCluster, namespace, svcName string) (string, error) {
 	ip, err := retry.DoWithRetryInterfaceE(
 		cluster.GetTesting(),
-		fmt.Sprintf("get the clusterIP of Service %s in namespace %s", svcName, namespace),
+		fmt.Sprintf("get the %s clusterIP of Service %s in namespace %s", serviceIPFamily, svcName, namespace),
 		60,
 		time.Second,
 		func() (interface{}, error) {
@@ -313,7 +321,16 @@ func getServiceIP(cluster *K8sCluster, namespace, svcName string) (string, error
 				return nil, errors.Wrapf(err, "could not get clusterIP")
 			}
 
-			return svc.Spec.ClusterIP, nil
+			for _, clusterIP := range svc.Spec.ClusterIPs {
+				if addr, err := netip.ParseAddr(clusterIP); err == nil && addr.Is6() == (serviceIPFamily == corev1.IPv6Protocol) {
+					return clusterIP, nil
+				}
+			}
+			if svc.Spec.IPFamilyPolicy == nil || *svc.Spec.IPFamilyPolicy == corev1.IPFamilyPolicySingleStack {
+				err = k8s.RunKubectlE(cluster.GetTesting(), cluster.GetKubectlOptions(namespace),
+					"patch", "service", svcName, "--type=merge", "--patch", `{"spec":{"ipFamilyPolicy":"PreferDualStack"}}`)
+			}
+			return nil, errors.Errorf("Service %s has no %s clusterIP yet, %v", svcName, serviceIPFamily, err)
 		},
 	)
 	if err != nil {
@@ -323,6 +340,31 @@ func getServiceIP(cluster *K8sCluster, namespace, svcName string) (string, error
 	return ip.(string), nil
 }
 
+// detectServiceIPFamily returns the IP family that the services are requested over. IPv6 is preferred on dual-stack
+// clusters, so that the IPv6 rules of the transparent proxy are covered as well.
+func detectServiceIPFamily(cluster *K8sCluster) (corev1.IPFamily, error) {
+	client, err := k8s.GetKubernetesClientFromOptionsE(cluster.GetTesting(), cluster.GetKubectlOptions())
+	if err != nil {
+		return "", err
+	}
+	ipFamily, err := cluster_providers.DetectIPFamily(context.Background(), client)
+	if err != nil {
+		return "", err
+	}
+	if ipFamily == clusters.IPv4 {
+		return corev1.IPv4Protocol, nil
+	}
+	return corev1.IPv6Protocol, nil
+}
+
+// urlHost brackets an IPv6 address, so that it can be the host of a URL
+func urlHost(host string) string {
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Is6() {
+		return "[" + host + "]"
+	}
+	return host
+}
+
 func setupHelmRepo(t testing.TestingT) {
 	repoName := strings.Split(Config.HelmChartName, "/")[0]
 
@@ -0,0 +1,161 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	// existing clusters are adopted by the suite itself, the other providers are registered by the callers
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/existing"
+	"github.com/kumahq/kuma-smoke/pkg/versions"
+	smoke_test "github.com/kumahq/kuma-smoke/test"
+	. "github.com/kumahq/kuma/test/framework"
+	. "github.com/onsi/ginkgo/v2"
+	corev1 "k8s.io/api/core/v1"
+	"os"
+)
+
+// SuiteOptions are the parameters of the Kubernetes smoke suite
+type SuiteOptions struct {
+	// EnvPlatform and EnvName identify the running cluster the specs are executed on
+	EnvPlatform string
+	EnvName     string
+	// ClusterOptions locate the cluster when its name is not enough, e.g. the kubeconfig of an existing cluster
+	ClusterOptions cluster_providers.Options
+
+	// PrevMinorVersion and PrevPatchVersion are resolved from the target version when both of them are empty.
+	// The upgrade from the previous patch is skipped when there is no previous patch version.
+	PrevMinorVersion string
+	PrevMinorKumactl string
+	PrevPatchVersion string
+	PrevPatchKumactl string
+
+	// ImageRegistry and ImageTag override the images of the tested version, e.g. to test images loaded into the cluster
+	// before they are published. The previous versions are still pulled from the registry of the configuration.
+	ImageRegistry string
+	ImageTag      string
+}
+
+var suiteOpts SuiteOptions
+var targetVersion, prevMinorVersion, prevPatchVersion semver.Version
+
+// publishedImageRegistry is the registry of the configuration that the previous versions are pulled from
+var publishedImageRegistry string
+
+var cluster *K8sCluster
+var kubeconfigPath string
+
+// serviceIPFamily is the IP family that the services are requested over, it is detected from the cluster
+var serviceIPFamily corev1.IPFamily
+
+// RegisterSuite declares all the specs of the Kubernetes smoke suite, it must be called once before running the specs.
+// The Kuma test framework configuration must be loaded before calling it, because the target version is read from it.
+func RegisterSuite(opts SuiteOptions) error {
+	if opts.EnvPlatform == "" || opts.EnvName == "" {
+		return fmt.Errorf("the platform and the name of a running Kubernetes cluster must be provided")
+	}
+	suiteOpts = opts
+
+	upgradeFrom, err := resolveUpgradeFrom(Config.KumaImageTag, opts.PrevMinorVersion, opts.PrevPatchVersion)
+	if err != nil {
+		return err
+	}
+	targetVersion = upgradeFrom.Target
+	prevMinorVersion = upgradeFrom.PrevMinor
+	if upgradeFrom.PrevPatch != nil {
+		prevPatchVersion = *upgradeFrom.PrevPatch
+	}
+
+	publishedImageRegistry = Config.KumaImageRegistry
+	if opts.ImageRegistry != "" {
+		Config.KumaImageRegistry = opts.ImageRegistry
+	}
+	if opts.ImageTag != "" {
+		Config.KumaImageTag = opts.ImageTag
+	}
+
+	Describe("Single Zone on Kubernetes - Install", Install, Ordered)
+	Describe("Single Zone on Kubernetes - Upgrade", Upgrade, Ordered)
+
+	SynchronizedBeforeSuite(func() {
+		file, err := os.CreateTemp("", "kuma-smoke")
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create temp file: %s", err))
+		}
+		_ = file.Close()
+		kubeconfigPath = file.Name()
+		cluster = NewK8sCluster(NewTestingT(), "kuma-smoke", true)
+		cluster.WithKubeConfig(kubeconfigPath)
+
+		if err := smoke_test.ExportKubeConfig(suiteOpts.EnvPlatform, suiteOpts.EnvName, suiteOpts.ClusterOptions, kubeconfigPath); err != nil {
+			panic(err.Error())
+		}
+		if serviceIPFamily, err = detectServiceIPFamily(cluster); err != nil {
+			panic(fmt.Sprintf("Failed to detect the IP family of the cluster: %s", err))
+		}
+		Logf("requesting the services over %s", serviceIPFamily)
+	}, func() {})
+
+	SynchronizedAfterSuite(func() {}, func() {
+		_ = os.Remove(kubeconfigPath)
+	})
+	return nil
+}
+
+// resolveUpgradeFrom parses the previous versions when they are provided, otherwise it resolves them from the target version
+func resolveUpgradeFrom(target, prevMinor, prevPatch string) (versions.UpgradeFrom, error) {
+	if prevMinor == "" && prevPatch == "" {
+		return versions.Resolve(target, nil)
+	}
+
+	var upgradeFrom versions.UpgradeFrom
+	var err error
+	if upgradeFrom.Target, err = versions.Parse(target); err != nil {
+		return upgradeFrom, err
+	}
+	if upgradeFrom.PrevMinor, err = versions.Parse(prevMinor); err != nil {
+		return upgradeFrom, err
+	}
+	if prevPatch != "" {
+		v, err := versions.Parse(prevPatch)
+		if err != nil {
+			return upgradeFrom, err
+		}
+		upgradeFrom.PrevPatch = &v
+	}
+	return upgradeFrom, nil
+}
+
+func createKumaDeployOptions(installMode InstallationMode, cni cniMode, version string) []KumaDeploymentOption {
+	opts := []KumaDeploymentOption{
+		WithInstallationMode(installMode),
+	}
+
+	if installMode == HelmInstallationMode {
+		opts = append(opts,
+			WithHelmOpt("controlPlane.resources.requests.cpu", "1"),
+			WithHelmOpt("controlPlane.resources.requests.memory", "2Gi"),
+			WithHelmOpt("controlPlane.resources.limits.memory", "4Gi"),
+			WithHelmChartPath(Config.HelmChartName),
+			WithHelmChartVersion(version),
+			WithHelmReleaseName(fmt.Sprintf("smoke-%s-%s", installMode, cni)),
+		)
+		// the images of the chart version are used, unless the images of the tested version are overridden
+		if version != targetVersion.String() || suiteOpts.ImageTag == "" {
+			opts = append(opts, WithoutHelmOpt("global.image.tag"))
+		}
+	} else {
+		opts = append(opts,
+			WithCtlOpts(map[string]string{
+				"--set": "" +
+					fmt.Sprintf("%scontrolPlane.resources.requests.cpu=1,", Config.HelmSubChartPrefix) +
+					fmt.Sprintf("%scontrolPlane.resources.requests.memory=2Gi,", Config.HelmSubChartPrefix) +
+					fmt.Sprintf("%scontrolPlane.resources.limits.memory=4Gi", Config.HelmSubChartPrefix),
+			}))
+	}
+
+	if cni == cniEnabled {
+		opts = append(opts, WithCNI())
+	}
+
+	return opts
+}
@@ -1,4 +1,4 @@
-package kubernetes_test
+package kubernetes
 
 import (
 	"encoding/json"
@@ -27,6 +27,10 @@ func Upgrade() {
 	stabilizationDuration := 30 * time.Second
 
 	DescribeTableSubtree("upgrade Kuma with a running workload", func(prevVersion semver.Version, installMode InstallationMode, cni cniMode) {
+		if prevVersion.EQ(semver.Version{}) {
+			Logf("Skipping because there is no previous version of %s to upgrade from", targetVersion)
+			return
+		}
 		if prevVersion.String() == targetVersion.String() {
 			Logf("Skipping because the previous version is the same as the current version %s", targetVersion)
 			return
@@ -37,17 +41,17 @@ func Upgrade() {
 		}
 		targetVerKumactl := Config.KumactlBin
 		targetVerImageTag := Config.KumaImageTag
+		targetVerImageRegistry := Config.KumaImageRegistry
 
 		BeforeAll(func() {
 			Logf("Testing upgrading from %s to %s", prevVersion, targetVersion)
-			versionEnvName := "KUMACTLBIN_PREV_MINOR"
+			prevKumactl := suiteOpts.PrevMinorKumactl
 			if prevVersion.String() == prevPatchVersion.String() {
-				versionEnvName = "KUMACTLBIN_PREV_PATCH"
+				prevKumactl = suiteOpts.PrevPatchKumactl
 			}
-			prevKumactl := os.Getenv(versionEnvName)
 			if installMode == KumactlInstallationMode {
 				if prevKumactl == "" {
-					Fail(fmt.Sprintf("Please set path to version %s kumactl using envirionment variable %s", prevVersion, versionEnvName))
+					Fail(fmt.Sprintf("Please provide the path to kumactl of version %s", prevVersion))
 					return
 				}
 				Config.KumactlBin = prevKumactl
@@ -55,6 +59,7 @@ func Upgrade() {
 			} else {
 				setupHelmRepo(cluster.GetTesting())
 			}
+			Config.KumaImageRegistry = publishedImageRegistry
 
 			err := NewClusterSetup().
 				Install(Kuma(core.Zone, createKumaDeployOptions(installMode, cni, prevVersion.String())...)).
@@ -70,6 +75,7 @@ func Upgrade() {
 			cluster.SetCP(nil)
 			Config.KumactlBin = targetVerKumactl
 			Config.KumaImageTag = targetVerImageTag
+			Config.KumaImageRegistry = targetVerImageRegistry
 		})
 
 		It("should run the demo app with mTLS and gateways", func() {
@@ -143,6 +149,7 @@ func Upgrade() {
 			// upgrade the CP to the new version (the target version of the testing)
 			Config.KumactlBin = targetVerKumactl
 			Config.KumaImageTag = targetVerImageTag
+			Config.KumaImageRegistry = targetVerImageRegistry
 			cluster.GetKumactlOptions().Kumactl = Config.KumactlBin
 			kumaDeployOpts := createKumaDeployOptions(installMode, cni, targetVersion.String())
 			if installMode == KumactlInstallationMode {
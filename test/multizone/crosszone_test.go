@@ -0,0 +1,120 @@
+package multizone_test
+
+import (
+	"fmt"
+	"github.com/kumahq/kuma/test/framework/client"
+	"github.com/kumahq/kuma/test/framework/deployments/democlient"
+	"github.com/kumahq/kuma/test/framework/deployments/testserver"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kumahq/kuma/pkg/config/core"
+	. "github.com/kumahq/kuma/test/framework"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func CrossZone() {
+	meshName := "multizone"
+	demoClient := "demo-client"
+	testServer := "test-server"
+
+	BeforeAll(func() {
+		err := NewClusterSetup().
+			Install(Kuma(core.Global)).
+			Install(MTLSMeshKubernetes(meshName)).
+			Install(MeshTrafficPermissionAllowAllKubernetes(meshName)).
+			Setup(global)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, zone := range zones {
+			err := NewClusterSetup().
+				Install(Kuma(core.Zone,
+					WithIngress(),
+					WithGlobalAddress(global.GetKuma().GetKDSServerAddress()),
+				)).
+				Install(NamespaceWithSidecarInjection(TestNamespace)).
+				Setup(zone)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		// the client runs in the first zone and the server runs in the last zone,
+		// so every request has to leave the zone of the client
+		Expect(zones[0].Install(democlient.Install(
+			democlient.WithNamespace(TestNamespace),
+			democlient.WithMesh(meshName),
+		))).To(Succeed())
+		serverZone := zones[len(zones)-1]
+		Expect(serverZone.Install(testserver.Install(
+			testserver.WithNamespace(TestNamespace),
+			testserver.WithMesh(meshName),
+			testserver.WithName(testServer),
+			testserver.WithEchoArgs("--instance", serverZone.ZoneName()),
+		))).To(Succeed())
+	})
+
+	E2EAfterAll(func() {
+		for _, zone := range zones {
+			Expect(zone.DeleteNamespace(TestNamespace)).To(Succeed())
+			Expect(zone.DeleteKuma()).To(Succeed())
+		}
+		Expect(global.DeleteKuma()).To(Succeed())
+	})
+
+	It("should connect all the zones to the global control plane", func() {
+		Eventually(func(g Gomega) {
+			out, err := global.GetKumactlOptions().RunKumactlAndGetOutput("inspect", "zones")
+			g.Expect(err).ToNot(HaveOccurred())
+			for _, zone := range zones {
+				g.Expect(zoneStatusLine(out, zone.ZoneName())).To(ContainSubstring("Online"))
+			}
+		}, "180s", "3s").Should(Succeed())
+
+		By("all the zone ingresses should be online")
+		Eventually(func(g Gomega) {
+			out, err := global.GetKumactlOptions().RunKumactlAndGetOutput("inspect", "zoneingresses")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Count(out, "Online")).To(Equal(len(zones)))
+		}, "180s", "3s").Should(Succeed())
+	})
+
+	It("should request a service in another zone through the ZoneIngress with mTLS", func() {
+		// ZoneIngress only accepts mTLS traffic, so a successful response also proves
+		// that the certificates issued by the global control plane are trusted across zones
+		serverZone := zones[len(zones)-1]
+		url := fmt.Sprintf("%s_%s_svc_80.mesh", testServer, TestNamespace)
+		Eventually(func(g Gomega) {
+			resp, err := client.CollectEchoResponse(zones[0], demoClient, url,
+				client.FromKubernetesPod(TestNamespace, demoClient))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(resp.Instance).To(Equal(serverZone.ZoneName()))
+		}, "180s", "3s").Should(Succeed())
+	})
+
+	It("should maintain stable control planes", func() {
+		time.Sleep(10 * time.Second)
+
+		for _, cluster := range append([]*K8sCluster{global}, zones...) {
+			Expect(CpRestarted(cluster)).To(BeFalse(), cluster.Name()+" restarted in this suite, this should not happen.")
+
+			logOutputFile := filepath.Join(Config.DebugDir, fmt.Sprintf("%s-multizone-logs-%s.log",
+				Config.KumaServiceName, cluster.Name()))
+			logs, err := cluster.GetKumaCPLogs()
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(os.WriteFile(logOutputFile, []byte(logs), 0o600)).To(Succeed())
+		}
+	})
+}
+
+// zoneStatusLine returns the line of a zone in the output of "kumactl inspect zones"
+func zoneStatusLine(out, zoneName string) string {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == zoneName {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package multizone_test
+
+import (
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/cluster-providers"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/aks"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/eks"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/gke"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/k3d"
+	_ "github.com/kumahq/kuma-smoke/pkg/cluster-providers/kind"
+	"github.com/kumahq/kuma-smoke/pkg/utils"
+	smoke_test "github.com/kumahq/kuma-smoke/test"
+	"github.com/kumahq/kuma/pkg/test"
+	. "github.com/kumahq/kuma/test/framework"
+	. "github.com/onsi/ginkgo/v2"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestE2E(t *testing.T) {
+	test.RunE2ESpecs(t, "Kuma Smoke Suite - Multizone")
+}
+
+var (
+	_ = Describe("Multizone on Kubernetes - Cross-zone traffic", CrossZone, Ordered)
+)
+
+var global *K8sCluster
+var zones []*K8sCluster
+var kubeconfigPaths []string
+
+var _ = SynchronizedBeforeSuite(func() {
+	envType := os.Getenv("SMOKE_ENV_TYPE")
+	envName := os.Getenv("SMOKE_ENV_NAME")
+	if envType == "" || envName == "" {
+		panic("SMOKE_ENV_TYPE and SMOKE_ENV_NAME must be set to provide a running multizone environment")
+	}
+	zoneCount := 2
+	if zonesStr := os.Getenv("SMOKE_ZONES"); zonesStr != "" {
+		var err error
+		zoneCount, err = strconv.Atoi(zonesStr)
+		// with a single zone the client and the server would run in the same zone, so no traffic would cross zones
+		if err != nil || zoneCount < 2 {
+			panic(fmt.Sprintf("Invalid number of zones: %s, at least 2 zones are required", zonesStr))
+		}
+	}
+
+	// SMOKE_ENV_REGION locates the clusters on cloud platforms when they are not in the region set by the environment variables
+	clusterOpts := cluster_providers.Options{Region: os.Getenv("SMOKE_ENV_REGION")}
+
+	// kind and k3d clusters share a docker network, so the zones can reach the global control plane through node ports.
+	// Clusters on cloud platforms are isolated, so the global control plane has to be exposed by a load balancer.
+	if envType != "kind" && envType != "k3d" {
+		Config.UseLoadBalancer = true
+		Config.UseHostnameInsteadOfIP = envType == "eks"
+	}
+
+	for i, clusterName := range utils.MultizoneClusterNames(envName, zoneCount) {
+		file, err := os.CreateTemp("", "kuma-smoke")
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create temp file: %s", err))
+		}
+		_ = file.Close()
+		kubeconfigPaths = append(kubeconfigPaths, file.Name())
+
+		if err := smoke_test.ExportKubeConfig(envType, clusterName, clusterOpts, file.Name()); err != nil {
+			panic(err.Error())
+		}
+
+		cluster := NewK8sCluster(NewTestingT(), clusterName, Silent)
+		cluster.WithKubeConfig(file.Name())
+		if i == 0 {
+			global = cluster
+		} else {
+			zones = append(zones, cluster)
+		}
+	}
+}, func() {})
+
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	for _, kubeconfigPath := range kubeconfigPaths {
+		_ = os.Remove(kubeconfigPath)
+	}
+})
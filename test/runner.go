@@ -0,0 +1,75 @@
+package test
+
+import (
+	"embed"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/kumahq/kuma/pkg/config"
+	"github.com/kumahq/kuma/pkg/core"
+	"github.com/kumahq/kuma/pkg/core/plugins"
+	core_apis "github.com/kumahq/kuma/pkg/core/resources/apis"
+	"github.com/kumahq/kuma/pkg/plugins/policies"
+	"github.com/kumahq/kuma/test/framework"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"time"
+)
+
+//go:embed cfg/*.yaml
+var configFiles embed.FS
+
+// LoadE2EConfig loads the test framework configuration of the product on the platform (e.g. cfg/kuma-kind.yaml),
+// it replaces setting E2E_CONFIG_FILE when the smoke tests are running inside the kuma-smoke binary
+func LoadE2EConfig(product, platform string) error {
+	fileName := fmt.Sprintf("cfg/%s-%s.yaml", product, platform)
+	content, err := configFiles.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("no test configuration found for %s on %s: %w", product, platform, err)
+	}
+
+	if err := config.NewLoader(&framework.Config).Load(nil, content, ""); err != nil {
+		return fmt.Errorf("failed to load test configuration %s: %w", fileName, err)
+	}
+	return framework.Config.AutoConfigure()
+}
+
+// RunOptions configures running the registered specs in-process
+type RunOptions struct {
+	Timeout        time.Duration
+	JSONReportFile string
+}
+
+// RunSpecs runs the registered specs in the current process without the ginkgo CLI and reports whether all of them passed.
+// It sets up ginkgo and gomega the same way as test.RunE2ESpecs from Kuma does for "go test".
+func RunSpecs(description string, opts RunOptions) bool {
+	plugins.InitAll(core_apis.NameToModule)
+	plugins.InitAll(policies.NameToModule)
+	gomega.SetDefaultConsistentlyDuration(time.Second * 5)
+	gomega.SetDefaultConsistentlyPollingInterval(time.Millisecond * 200)
+	gomega.SetDefaultEventuallyPollingInterval(time.Millisecond * 500)
+	gomega.SetDefaultEventuallyTimeout(time.Second * 30)
+	format.MaxLength = 100000
+
+	core.SetLogger = func(l logr.Logger) {}
+	log.SetLogger(zap.New(
+		zap.UseDevMode(true),
+		zap.WriteTo(ginkgo.GinkgoWriter),
+	))
+	gomega.RegisterFailHandler(ginkgo.Fail)
+
+	suiteConfig, reporterConfig := ginkgo.GinkgoConfiguration()
+	suiteConfig.Timeout = opts.Timeout
+	reporterConfig.Verbose = true
+	reporterConfig.JSONReport = opts.JSONReportFile
+
+	return ginkgo.RunSpecs(&testingT{}, description, suiteConfig, reporterConfig)
+}
+
+// testingT satisfies ginkgo.GinkgoTestingT when there is no *testing.T, the result is returned by RunSpecs instead
+type testingT struct{}
+
+func (t *testingT) Helper() {}
+func (t *testingT) Fail()   {}
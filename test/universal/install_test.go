@@ -0,0 +1,122 @@
+package universal_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/universal"
+	"github.com/kumahq/kuma/test/framework/client"
+	"github.com/kumahq/kuma/test/framework/utils"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/kumahq/kuma/test/framework"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func Install() {
+	meshName := "smoke"
+	testServer := "test-server"
+	demoClient := "demo-client"
+	testServerURL := "test-server.mesh"
+
+	BeforeAll(func() {
+		err := NewClusterSetup().
+			Install(MeshUniversal(meshName)).
+			Install(TestServerUniversal(testServer, meshName, WithArgs([]string{"echo", "--instance", "universal-1"}))).
+			Install(DemoClientUniversal(demoClient, meshName, WithTransparentProxy(true))).
+			Setup(cluster)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	E2EAfterAll(func() {
+		Expect(cluster.DeleteMeshApps(meshName)).To(Succeed())
+		Expect(cluster.DeleteMesh(meshName)).To(Succeed())
+	})
+
+	It("should deploy mesh wide policy", func() {
+		policy := `
+type: MeshRateLimit
+name: mesh-rate-limit
+mesh: %s
+spec:
+  targetRef:
+    kind: Mesh
+    proxyTypes:
+      - Sidecar
+  from:
+    - targetRef:
+        kind: Mesh
+      default:
+        local:
+          http:
+            requestRate:
+              num: 10000
+              interval: 1s
+            onRateLimit:
+              status: 429
+`
+		Expect(cluster.Install(YamlUniversal(fmt.Sprintf(policy, meshName)))).To(Succeed())
+	})
+
+	It("should support requesting the test server from the demo client", func() {
+		Eventually(func(g Gomega) {
+			resp, err := client.CollectEchoResponse(cluster, demoClient, testServerURL)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(resp.Instance).To(Equal("universal-1"))
+		}, "30s", "1s").Should(Succeed())
+	})
+
+	It("should distribute certs when mTLS is enabled", func() {
+		By("enable mTLS on the mesh")
+		Expect(cluster.Install(MTLSMeshUniversal(meshName))).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			out, _, err := cluster.GetKuma().(*UniversalControlPlane).Exec(
+				"curl", "--fail", "--silent", "http://localhost:5681/mesh-insights/"+meshName)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			insight := meshInsightResponse{}
+			g.Expect(json.Unmarshal([]byte(out), &insight)).To(Succeed())
+			g.Expect(insight.MTLS.IssuedBackends["ca-1"].Total).To(BeNumerically(">", 0))
+		}, "60s", "1s").Should(Succeed())
+
+		By("the test server should not be requested without a MeshTrafficPermission applied")
+		Eventually(func(g Gomega) {
+			resp, err := client.CollectFailure(cluster, demoClient, testServerURL)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(resp.ResponseCode).To(Equal(403))
+		}, "30s", "1s").Should(Succeed())
+
+		By("the test server should be requested successfully with a MeshTrafficPermission applied")
+		Expect(cluster.Install(MeshTrafficPermissionAllowAllUniversal(meshName))).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			_, err := client.CollectEchoResponse(cluster, demoClient, testServerURL)
+			g.Expect(err).ToNot(HaveOccurred())
+		}, "30s", "1s").Should(Succeed())
+	})
+
+	It("should maintain a stable control plane", func() {
+		time.Sleep(10 * time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		logs, err := universal.ControlPlaneLogs(ctx, cluster.Name())
+		Expect(err).ToNot(HaveOccurred())
+
+		logOutputFile := filepath.Join(Config.DebugDir, fmt.Sprintf("%s-install-logs-universal.log", Config.KumaServiceName))
+		Expect(os.WriteFile(logOutputFile, []byte(logs), 0o600)).To(Succeed())
+		Expect(utils.HasPanicInCpLogs(logs)).To(BeFalse(), "the control plane panicked in this suite, this should not happen.")
+	})
+}
+
+type meshInsightResponse struct {
+	MTLS struct {
+		IssuedBackends map[string]struct {
+			Total int `json:"total"`
+		} `json:"issuedBackends"`
+	} `json:"mTLS"`
+}
@@ -0,0 +1,51 @@
+package universal_test
+
+import (
+	"fmt"
+	"github.com/kumahq/kuma-smoke/pkg/universal"
+	"github.com/kumahq/kuma/pkg/config/core"
+	"github.com/kumahq/kuma/pkg/test"
+	. "github.com/kumahq/kuma/test/framework"
+	. "github.com/onsi/ginkgo/v2"
+	"os"
+	"testing"
+)
+
+func TestE2E(t *testing.T) {
+	test.RunE2ESpecs(t, "Kuma Smoke Suite - Universal")
+}
+
+var (
+	_ = Describe("Single Zone on Universal - Install", Install, Ordered)
+)
+
+var cluster *UniversalCluster
+
+var _ = SynchronizedBeforeSuite(func() {
+	statePath := os.Getenv("SMOKE_ENV_STATE")
+	if statePath == "" {
+		panic("SMOKE_ENV_STATE must be set to provide a running Universal control plane")
+	}
+
+	state, err := universal.LoadState(statePath)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load the state of the Universal environment: %v", err))
+	}
+
+	// dataplane containers are named after the cluster, so that they are removed together with the environment
+	cluster = NewUniversalCluster(NewTestingT(), state.Name, Silent)
+	cp, err := NewUniversalControlPlane(cluster.GetTesting(), core.Zone, cluster.Name(), cluster.Verbose(),
+		UniversalNetworking{
+			IP:            state.KumaCp.IP,
+			ApiServerPort: state.KumaCp.ApiServerPort,
+			SshPort:       state.KumaCp.SshPort,
+		}, nil, true)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to connect to the control plane of environment %s: %v", state.Name, err))
+	}
+	cluster.SetCp(cp)
+}, func() {})
+
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	_ = cluster.DismissCluster()
+})